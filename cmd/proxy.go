@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/5gsec/api-speculator/internal/core"
+	"github.com/5gsec/api-speculator/internal/util"
+)
+
+func init() {
+	ProxyCmd.Flags().String("listen", "", "proxy address, defaults to :8080")
+	ProxyCmd.Flags().String("upstream", "", "URL of the proxied service")
+	ProxyCmd.Flags().String("admin-listen", "", "address serving the live findings on /findings")
+	for key, flag := range map[string]string{
+		"proxy.listen":      "listen",
+		"proxy.upstream":    "upstream",
+		"proxy.adminListen": "admin-listen",
+	} {
+		if err := viper.BindPFlag(key, ProxyCmd.Flags().Lookup(flag)); err != nil {
+			panic(err)
+		}
+	}
+	RootCmd.AddCommand(ProxyCmd)
+}
+
+var ProxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Run a reverse proxy evaluating the proxied traffic against the API specifications",
+	Long: `proxy runs a local HTTP reverse proxy in front of an upstream service.
+
+Every proxied request is evaluated against the API specifications as it flows, shadow and zombie API hits are logged
+live and, if enabled, served by the admin endpoint. The JSON report of the session is exported on exit.
+`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		util.InitLogger(debugMode)
+		logBuildInfo(util.GetLogger())
+		ctx := setupSignalHandler()
+		return core.RunProxy(ctx, configFilePath)
+	},
+}
@@ -10,6 +10,7 @@ import (
 	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/5gsec/api-speculator/internal/core"
 	"github.com/5gsec/api-speculator/internal/util"
@@ -23,6 +24,17 @@ var (
 func init() {
 	RootCmd.PersistentFlags().StringVar(&configFilePath, "config", "", "config file path")
 	RootCmd.PersistentFlags().BoolVar(&debugMode, "debug", false, "run in debug mode")
+
+	RootCmd.Flags().String("since", "", "scan the traffic observed since, e.g. 2024-05-01 or 7d")
+	RootCmd.Flags().String("until", "", "scan the traffic observed until, e.g. 2024-05-08T00:00:00Z or 1d")
+	for key, flag := range map[string]string{
+		"traffic.since": "since",
+		"traffic.until": "until",
+	} {
+		if err := viper.BindPFlag(key, RootCmd.Flags().Lookup(flag)); err != nil {
+			panic(err)
+		}
+	}
 }
 
 var RootCmd = &cobra.Command{
@@ -37,16 +49,17 @@ By analyzing API traffic in conjunction with your API specifications (e.g., Open
   * Orphan APIs: Endpoints that are defined in your API specification but are never invoked in the observed traffic.
 `,
 	//Long: `A Utility to identify Shadow and Zombie APIs provided API Specification`,
-	Run: func(cmd *cobra.Command, args []string) {
-		run()
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return run()
 	},
 }
 
-func run() {
+func run() error {
 	util.InitLogger(debugMode)
 	logBuildInfo(util.GetLogger())
 	ctx := setupSignalHandler()
-	core.Run(ctx, configFilePath)
+	return core.Run(ctx, configFilePath)
 }
 
 // setupSignalHandler registers for SIGTERM and SIGINT. A context is returned
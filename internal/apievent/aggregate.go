@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"context"
+	"time"
+)
+
+// maxAggregatedEvents bounds the number of distinct events an aggregator holds
+// before flushing them.
+const maxAggregatedEvents = 10000
+
+// aggregator sums the occurrences of identical events and widens their first
+// and last seen times, keeping the order they were first seen in. The events
+// are flushed once the aggregator is full, so identical events may be emitted
+// more than once.
+type aggregator struct {
+	ctx          context.Context
+	emit         func(ApiEvent) error
+	maxEvents    int
+	indexByEvent map[ApiEvent]int
+	events       []ApiEvent
+}
+
+func newAggregator(ctx context.Context, emit func(ApiEvent) error) *aggregator {
+	return &aggregator{
+		ctx:          ctx,
+		emit:         emit,
+		maxEvents:    maxAggregatedEvents,
+		indexByEvent: make(map[ApiEvent]int),
+	}
+}
+
+// add counts the event, events without occurrences count once.
+func (a *aggregator) add(event ApiEvent) error {
+	occurrences := event.Occurrences
+	if occurrences == 0 {
+		occurrences = 1
+	}
+	firstSeen, lastSeen := event.FirstSeen, event.LastSeen
+	event.Occurrences = 0
+	event.FirstSeen, event.LastSeen = time.Time{}, time.Time{}
+
+	idx, exists := a.indexByEvent[event]
+	if !exists {
+		if len(a.events) >= a.maxEvents {
+			if err := a.flush(); err != nil {
+				return err
+			}
+		}
+		idx = len(a.events)
+		a.indexByEvent[event] = idx
+		a.events = append(a.events, event)
+	}
+	a.events[idx].Occurrences += occurrences
+	MergeSeen(&a.events[idx].FirstSeen, &a.events[idx].LastSeen, firstSeen, lastSeen)
+	return nil
+}
+
+// flush calls emit for every aggregated event and resets the aggregator.
+func (a *aggregator) flush() error {
+	for _, event := range a.events {
+		if err := a.ctx.Err(); err != nil {
+			return err
+		}
+		if err := a.emit(event); err != nil {
+			return err
+		}
+	}
+	a.events = a.events[:0]
+	clear(a.indexByEvent)
+	return nil
+}
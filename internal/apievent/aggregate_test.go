@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregator_FlushesWhenFull(t *testing.T) {
+	var events []ApiEvent
+	a := newAggregator(context.Background(), func(event ApiEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	a.maxEvents = 2
+
+	get := ApiEvent{RequestMethod: "GET", RequestPath: "/users"}
+	post := ApiEvent{RequestMethod: "POST", RequestPath: "/users"}
+	del := ApiEvent{RequestMethod: "DELETE", RequestPath: "/users"}
+	for _, event := range []ApiEvent{get, post, get, del, get} {
+		require.NoError(t, a.add(event))
+	}
+	require.NoError(t, a.flush())
+
+	assert.Equal(t, []ApiEvent{
+		{RequestMethod: "GET", RequestPath: "/users", Occurrences: 2},
+		{RequestMethod: "POST", RequestPath: "/users", Occurrences: 1},
+		{RequestMethod: "DELETE", RequestPath: "/users", Occurrences: 1},
+		{RequestMethod: "GET", RequestPath: "/users", Occurrences: 1},
+	}, events)
+}
+
+func TestAggregator_MergesSeenTimes(t *testing.T) {
+	var events []ApiEvent
+	a := newAggregator(context.Background(), func(event ApiEvent) error {
+		events = append(events, event)
+		return nil
+	})
+
+	first := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	last := first.Add(time.Hour)
+	require.NoError(t, a.add(ApiEvent{RequestMethod: "GET", RequestPath: "/users", FirstSeen: last, LastSeen: last}))
+	require.NoError(t, a.add(ApiEvent{RequestMethod: "GET", RequestPath: "/users", FirstSeen: first, LastSeen: first}))
+	require.NoError(t, a.add(ApiEvent{RequestMethod: "GET", RequestPath: "/users"}))
+	require.NoError(t, a.flush())
+
+	assert.Equal(t, []ApiEvent{
+		{RequestMethod: "GET", RequestPath: "/users", Occurrences: 3, FirstSeen: first, LastSeen: last},
+	}, events)
+}
@@ -3,11 +3,35 @@
 
 package apievent
 
+import (
+	"time"
+)
+
 type ApiEvent struct {
 	ClusterName   string `json:"cluster_name,omitempty"`
 	ServiceName   string `json:"service_name,omitempty"`
 	RequestMethod string `json:"request_method,omitempty"`
 	RequestPath   string `json:"request_path,omitempty"`
+	// RouteTemplate is the path template the server routed the request to, e.g.
+	// the OpenTelemetry `http.route`, if known.
+	RouteTemplate string `json:"route_template,omitempty"`
 	ResponseCode  int    `json:"response_code,omitempty"`
-	Occurrences   int    `json:"occurrences,omitempty"`
+	// ContentType is the Content-Type of the response, if known.
+	ContentType string `json:"content_type,omitempty"`
+	Occurrences int    `json:"occurrences,omitempty"`
+	// FirstSeen and LastSeen bound the times the occurrences were observed at,
+	// if known.
+	FirstSeen time.Time `json:"first_seen,omitzero"`
+	LastSeen  time.Time `json:"last_seen,omitzero"`
+}
+
+// MergeSeen widens the first and last seen times to include the other ones.
+// Unknown, i.e. zero, times are ignored.
+func MergeSeen(firstSeen, lastSeen *time.Time, otherFirstSeen, otherLastSeen time.Time) {
+	if !otherFirstSeen.IsZero() && (firstSeen.IsZero() || otherFirstSeen.Before(*firstSeen)) {
+		*firstSeen = otherFirstSeen
+	}
+	if !otherLastSeen.IsZero() && (lastSeen.IsZero() || otherLastSeen.After(*lastSeen)) {
+		*lastSeen = otherLastSeen
+	}
 }
@@ -0,0 +1,282 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/5gsec/api-speculator/internal/util"
+)
+
+// DefaultEnvoyFormat is Envoy's default access log format.
+const DefaultEnvoyFormat = `[%START_TIME%] "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %PROTOCOL%" ` +
+	`%RESPONSE_CODE% %RESPONSE_FLAGS% %BYTES_RECEIVED% %BYTES_SENT% %DURATION% %RESP(X-ENVOY-UPSTREAM-SERVICE-TIME)% ` +
+	`"%REQ(X-FORWARDED-FOR)%" "%REQ(USER-AGENT)%" "%REQ(X-REQUEST-ID)%" "%REQ(:AUTHORITY)%" "%UPSTREAM_HOST%"`
+
+// Envoy command operators holding the request fields, in order of preference.
+var (
+	envoyAuthorityOperators    = []string{"REQ(:AUTHORITY)", "REQ(HOST)"}
+	envoyMethodOperators       = []string{"REQ(:METHOD)"}
+	envoyPathOperators         = []string{"REQ(X-ENVOY-ORIGINAL-PATH?:PATH)", "REQ(X-ENVOY-ORIGINAL-PATH)", "REQ(:PATH)"}
+	envoyResponseCodeOperators = []string{"RESPONSE_CODE"}
+)
+
+// EnvoyJSONKeys names the keys of JSON access logs holding the request fields.
+type EnvoyJSONKeys struct {
+	Authority    string
+	Method       string
+	Path         string
+	ResponseCode string
+}
+
+// DefaultEnvoyJSONKeys are the keys of Istio's JSON access logs.
+var DefaultEnvoyJSONKeys = EnvoyJSONKeys{
+	Authority:    "authority",
+	Method:       "method",
+	Path:         "path",
+	ResponseCode: "response_code",
+}
+
+// withDefaults returns the keys, the keys that aren't set default to the ones
+// of DefaultEnvoyJSONKeys.
+func (k EnvoyJSONKeys) withDefaults() EnvoyJSONKeys {
+	return EnvoyJSONKeys{
+		Authority:    cmp.Or(k.Authority, DefaultEnvoyJSONKeys.Authority),
+		Method:       cmp.Or(k.Method, DefaultEnvoyJSONKeys.Method),
+		Path:         cmp.Or(k.Path, DefaultEnvoyJSONKeys.Path),
+		ResponseCode: cmp.Or(k.ResponseCode, DefaultEnvoyJSONKeys.ResponseCode),
+	}
+}
+
+// EnvoySource reads Envoy or Istio access logs from a file or from the
+// standard input. Lines starting with `{` are parsed as JSON access logs, other
+// lines using the text format. Identical requests are aggregated into a single
+// event, lines that can't be parsed are skipped.
+type EnvoySource struct {
+	Path string
+
+	// Format is the text access log format, defaults to DefaultEnvoyFormat.
+	Format string
+
+	// JSONKeys defaults to DefaultEnvoyJSONKeys, key by key.
+	JSONKeys EnvoyJSONKeys
+}
+
+// envoyTextFormat is a compiled text access log format.
+type envoyTextFormat struct {
+	re                *regexp.Regexp
+	authorityGroup    int
+	methodGroup       int
+	pathGroup         int
+	responseCodeGroup int
+}
+
+func (s *EnvoySource) Stream(ctx context.Context, emit func(ApiEvent) error) error {
+	format := s.Format
+	if format == "" {
+		format = DefaultEnvoyFormat
+	}
+	textFormat, err := compileEnvoyFormat(format)
+	if err != nil {
+		return err
+	}
+	jsonKeys := s.JSONKeys.withDefaults()
+
+	input, err := openInput(s.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open Envoy access logs: %w", err)
+	}
+	defer input.Close()
+
+	events := newAggregator(ctx, emit)
+	skippedLines := 0
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLineSize)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var event ApiEvent
+		var ok bool
+		if line[0] == '{' {
+			event, ok = parseEnvoyJSONLine(line, jsonKeys)
+		} else {
+			event, ok = textFormat.parse(string(line))
+		}
+		if !ok {
+			skippedLines++
+			continue
+		}
+		if err := events.add(event); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read Envoy access logs: %w", err)
+	}
+
+	if skippedLines > 0 {
+		util.GetLogger().Warnf("skipped %d Envoy access log lines that could not be parsed as HTTP requests", skippedLines)
+	}
+	return events.flush()
+}
+
+// compileEnvoyFormat turns an Envoy access log format string into a regular
+// expression capturing every command operator.
+func compileEnvoyFormat(format string) (*envoyTextFormat, error) {
+	textFormat := &envoyTextFormat{}
+	groupByOperator := make(map[string]int)
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	group := 0
+	for len(format) > 0 {
+		operator, length := nextEnvoyOperator(format)
+		if length == 0 {
+			pattern.WriteString(regexp.QuoteMeta(format[:1]))
+			format = format[1:]
+			continue
+		}
+
+		group++
+		pattern.WriteString("(.*?)")
+		if _, exists := groupByOperator[operator]; !exists {
+			groupByOperator[operator] = group
+		}
+		format = format[length:]
+	}
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid Envoy access log format: %w", err)
+	}
+	textFormat.re = re
+
+	textFormat.authorityGroup = findEnvoyOperator(groupByOperator, envoyAuthorityOperators)
+	textFormat.methodGroup = findEnvoyOperator(groupByOperator, envoyMethodOperators)
+	textFormat.pathGroup = findEnvoyOperator(groupByOperator, envoyPathOperators)
+	textFormat.responseCodeGroup = findEnvoyOperator(groupByOperator, envoyResponseCodeOperators)
+	if textFormat.methodGroup == 0 || textFormat.pathGroup == 0 {
+		return nil, fmt.Errorf("invalid Envoy access log format: the request method and path must be logged")
+	}
+	return textFormat, nil
+}
+
+// nextEnvoyOperator returns the command operator at the start of the format,
+// without its `%` delimiters and its max length, as well as its length in the
+// format. The length is 0 if the format doesn't start with an operator.
+func nextEnvoyOperator(format string) (string, int) {
+	if len(format) < 2 || format[0] != '%' {
+		return "", 0
+	}
+
+	i := 1
+	for i < len(format) && (format[i] >= 'A' && format[i] <= 'Z' || format[i] >= '0' && format[i] <= '9' || format[i] == '_') {
+		i++
+	}
+	if i == 1 || i == len(format) {
+		return "", 0
+	}
+	command := format[1:i]
+
+	if format[i] == '(' {
+		// The parameters may contain `%`, e.g. START_TIME(%Y-%m-%d).
+		end := strings.IndexByte(format[i:], ')')
+		if end == -1 {
+			return "", 0
+		}
+		command += strings.ToUpper(format[i : i+end+1])
+		i += end + 1
+	}
+	if i < len(format) && format[i] == ':' {
+		// Max length, e.g. %REQ(USER-AGENT):10%
+		i++
+		for i < len(format) && format[i] >= '0' && format[i] <= '9' {
+			i++
+		}
+	}
+	if i >= len(format) || format[i] != '%' {
+		return "", 0
+	}
+	return command, i + 1
+}
+
+func findEnvoyOperator(groupByOperator map[string]int, operators []string) int {
+	for _, operator := range operators {
+		if group, ok := groupByOperator[operator]; ok {
+			return group
+		}
+	}
+	return 0
+}
+
+func (f *envoyTextFormat) parse(line string) (ApiEvent, bool) {
+	matches := f.re.FindStringSubmatch(line)
+	if matches == nil {
+		return ApiEvent{}, false
+	}
+
+	field := func(group int) string {
+		if group == 0 {
+			return ""
+		}
+		return envoyValue(matches[group])
+	}
+	return newEnvoyEvent(field(f.authorityGroup), field(f.methodGroup), field(f.pathGroup), field(f.responseCodeGroup))
+}
+
+func parseEnvoyJSONLine(line []byte, jsonKeys EnvoyJSONKeys) (ApiEvent, bool) {
+	var fields map[string]any
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return ApiEvent{}, false
+	}
+
+	field := func(key string) string {
+		switch value := fields[key].(type) {
+		case string:
+			return envoyValue(value)
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		default:
+			return ""
+		}
+	}
+	return newEnvoyEvent(field(jsonKeys.Authority), field(jsonKeys.Method), field(jsonKeys.Path), field(jsonKeys.ResponseCode))
+}
+
+func newEnvoyEvent(authority, method, path, responseCode string) (ApiEvent, bool) {
+	if method == "" || path == "" {
+		return ApiEvent{}, false
+	}
+	code, _ := strconv.Atoi(responseCode)
+	return ApiEvent{
+		ServiceName:   authority,
+		RequestMethod: method,
+		RequestPath:   path,
+		ResponseCode:  code,
+	}, true
+}
+
+// envoyValue returns the logged value, empty if Envoy logged it as missing.
+func envoyValue(value string) string {
+	if value == "-" {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEnvoyLogs = `[2024-01-01T00:00:00.000Z] "GET /api/users/1 HTTP/1.1" 200 - 0 12 3 2 "-" "curl/8.0 (x86_64)" "a1" "users.default.svc:8080" "10.0.0.1:8080"
+[2024-01-01T00:00:01.000Z] "GET /api/users/1 HTTP/1.1" 200 - 0 12 3 2 "-" "curl/8.0 (x86_64)" "a2" "users.default.svc:8080" "10.0.0.1:8080"
+{"authority":"orders.default.svc","method":"POST","path":"/orders","response_code":201,"protocol":"HTTP/1.1"}
+[2024-01-01T00:00:02.000Z] "- - -" 0 UF 0 0 1 - "-" "-" "-" "-" "10.0.0.2:5432"
+not an access log line
+`
+
+func TestEnvoySource_Stream(t *testing.T) {
+	path := writeInput(t, "access.log", testEnvoyLogs)
+
+	events, err := collect(t, &EnvoySource{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, []ApiEvent{
+		{ServiceName: "users.default.svc:8080", RequestMethod: "GET", RequestPath: "/api/users/1", ResponseCode: 200, Occurrences: 2},
+		{ServiceName: "orders.default.svc", RequestMethod: "POST", RequestPath: "/orders", ResponseCode: 201, Occurrences: 1},
+	}, events)
+}
+
+func TestEnvoySource_Stream_CustomFormat(t *testing.T) {
+	path := writeInput(t, "access.log",
+		`2024-01-01T00:00:00 users.default.svc GET /users/1 /internal/users/1 404`+"\n"+
+			`{"host":"users.default.svc","verb":"DELETE","uri":"/users/2","status":"204"}`+"\n")
+
+	events, err := collect(t, &EnvoySource{
+		Path:     path,
+		Format:   `%START_TIME(%Y-%m-%dT%H:%M:%S)% %REQ(:AUTHORITY)% %REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH):100% %REQ(:PATH)% %RESPONSE_CODE%`,
+		JSONKeys: EnvoyJSONKeys{Authority: "host", Method: "verb", Path: "uri", ResponseCode: "status"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []ApiEvent{
+		{ServiceName: "users.default.svc", RequestMethod: "GET", RequestPath: "/users/1", ResponseCode: 404, Occurrences: 1},
+		{ServiceName: "users.default.svc", RequestMethod: "DELETE", RequestPath: "/users/2", ResponseCode: 204, Occurrences: 1},
+	}, events)
+}
+
+func TestEnvoySource_Stream_PartialJSONKeys(t *testing.T) {
+	path := writeInput(t, "access.log",
+		`{"authority":"users.default.svc","method":"GET","x_path":"/users/1","path":"/ignored","response_code":200}`+"\n")
+
+	events, err := collect(t, &EnvoySource{Path: path, JSONKeys: EnvoyJSONKeys{Path: "x_path"}})
+	require.NoError(t, err)
+	assert.Equal(t, []ApiEvent{
+		{ServiceName: "users.default.svc", RequestMethod: "GET", RequestPath: "/users/1", ResponseCode: 200, Occurrences: 1},
+	}, events)
+}
+
+func TestEnvoySource_Stream_InvalidFormat(t *testing.T) {
+	_, err := collect(t, &EnvoySource{
+		Path:   writeInput(t, "access.log", ""),
+		Format: `%START_TIME% %RESPONSE_CODE%`,
+	})
+	assert.ErrorContains(t, err, "method and path must be logged")
+}
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// HARSource reads the requests recorded in a HAR file, e.g. exported from a
+// browser or Postman, from a file or from the standard input. Identical
+// requests are aggregated into a single event.
+type HARSource struct {
+	Path string
+}
+
+type harEntry struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+	} `json:"request"`
+	Response struct {
+		Status  int `json:"status"`
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+func (s *HARSource) Stream(ctx context.Context, emit func(ApiEvent) error) error {
+	input, err := openInput(s.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open HAR file: %w", err)
+	}
+	defer input.Close()
+
+	events := newAggregator(ctx, emit)
+	if err := decodeHAREntries(ctx, input, func(entry harEntry) error {
+		event, err := entry.toApiEvent()
+		if err != nil {
+			return err
+		}
+		return events.add(event)
+	}); err != nil {
+		return fmt.Errorf("failed to decode HAR file: %w", err)
+	}
+
+	return events.flush()
+}
+
+func (e harEntry) toApiEvent() (ApiEvent, error) {
+	u, err := url.Parse(e.Request.URL)
+	if err != nil {
+		return ApiEvent{}, fmt.Errorf("invalid request URL `%s`: %w", e.Request.URL, err)
+	}
+	return ApiEvent{
+		ServiceName:   u.Host,
+		RequestMethod: e.Request.Method,
+		RequestPath:   u.RequestURI(),
+		ResponseCode:  e.Response.Status,
+		ContentType:   e.Response.Content.MimeType,
+	}, nil
+}
+
+// decodeHAREntries decodes the `log.entries` one at a time, so that large HAR
+// files don't need to be held in memory.
+func decodeHAREntries(ctx context.Context, r io.Reader, fn func(harEntry) error) error {
+	decoder := json.NewDecoder(r)
+
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+	if err := seekKey(decoder, "log"); err != nil {
+		return err
+	}
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+	if err := seekKey(decoder, "entries"); err != nil {
+		return err
+	}
+	if err := expectDelim(decoder, '['); err != nil {
+		return err
+	}
+
+	for decoder.More() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var entry harEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return err
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected `%v`, got `%v`", delim, token)
+	}
+	return nil
+}
+
+// seekKey skips the object members until the given key, leaving the decoder at
+// its value.
+func seekKey(decoder *json.Decoder, key string) error {
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if token == key {
+			return nil
+		}
+		var skipped json.RawMessage
+		if err := decoder.Decode(&skipped); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("missing `%s` key", key)
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHAR = `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "test", "version": "1"},
+    "pages": [{"id": "page_1", "title": "home"}],
+    "entries": [
+      {
+        "startedDateTime": "2024-01-01T00:00:00Z",
+        "request": {"method": "GET", "url": "https://api.example.com:8443/users/1?fields=name", "headers": []},
+        "response": {"status": 200, "content": {"size": 2, "text": "{}"}}
+      },
+      {
+        "request": {"method": "GET", "url": "https://api.example.com:8443/users/1?fields=name"},
+        "response": {"status": 200}
+      },
+      {
+        "request": {"method": "POST", "url": "http://web.example.com/login"},
+        "response": {"status": 302}
+      }
+    ]
+  }
+}`
+
+func TestHARSource_Stream(t *testing.T) {
+	path := writeInput(t, "session.har", testHAR)
+
+	events, err := collect(t, &HARSource{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, []ApiEvent{
+		{ServiceName: "api.example.com:8443", RequestMethod: "GET", RequestPath: "/users/1?fields=name", ResponseCode: 200, Occurrences: 2},
+		{ServiceName: "web.example.com", RequestMethod: "POST", RequestPath: "/login", ResponseCode: 302, Occurrences: 1},
+	}, events)
+}
+
+func TestHARSource_Stream_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"not an object":   `[]`,
+		"missing log":     `{"other": {}}`,
+		"missing entries": `{"log": {"version": "1.2"}}`,
+		"invalid entry":   `{"log": {"entries": [{"request": "GET /"}]}}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := collect(t, &HARSource{Path: writeInput(t, "session.har", content)})
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const maxJSONLLineSize = 10 * 1024 * 1024
+
+// JSONLSource reads newline-delimited JSON, one ApiEvent per line, from a file
+// or from the standard input. Events without occurrences count once.
+type JSONLSource struct {
+	Path string
+}
+
+func (s *JSONLSource) Stream(ctx context.Context, emit func(ApiEvent) error) error {
+	input, err := openInput(s.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open JSONL events: %w", err)
+	}
+	defer input.Close()
+
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLineSize)
+	lineNumber := 0
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		lineNumber++
+
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var event ApiEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return fmt.Errorf("failed to decode JSONL event at line %d: %w", lineNumber, err)
+		}
+		if event.Occurrences == 0 {
+			event.Occurrences = 1
+		}
+		if err := emit(event); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read JSONL events: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeInput(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func collect(t *testing.T, source EventSource) ([]ApiEvent, error) {
+	t.Helper()
+	var events []ApiEvent
+	err := source.Stream(context.Background(), func(event ApiEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	return events, err
+}
+
+func TestJSONLSource_Stream(t *testing.T) {
+	path := writeInput(t, "events.jsonl", `{"cluster_name":"prod","service_name":"users","request_method":"GET","request_path":"/users/1","response_code":200,"occurrences":3}
+
+{"service_name":"users","request_method":"DELETE","request_path":"/users/1","response_code":204}
+`)
+
+	events, err := collect(t, &JSONLSource{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, []ApiEvent{
+		{ClusterName: "prod", ServiceName: "users", RequestMethod: "GET", RequestPath: "/users/1", ResponseCode: 200, Occurrences: 3},
+		{ServiceName: "users", RequestMethod: "DELETE", RequestPath: "/users/1", ResponseCode: 204, Occurrences: 1},
+	}, events)
+}
+
+func TestJSONLSource_Stream_Errors(t *testing.T) {
+	path := writeInput(t, "events.jsonl", "{\"request_path\":\"/users\"}\nnot json\n")
+	_, err := collect(t, &JSONLSource{Path: path})
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = collect(t, &JSONLSource{Path: filepath.Join(t.TempDir(), "missing.jsonl")})
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = (&JSONLSource{Path: path}).Stream(ctx, func(ApiEvent) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,245 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/5gsec/api-speculator/internal/util"
+)
+
+// Predefined access log formats.
+const (
+	NginxFormatCommon   = `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent`
+	NginxFormatCombined = NginxFormatCommon + ` "$http_referer" "$http_user_agent"`
+)
+
+var (
+	nginxFormats = map[string]string{
+		"common":   NginxFormatCommon,
+		"combined": NginxFormatCombined,
+	}
+
+	nginxVariablePattern = regexp.MustCompile(`\$(?:\{([a-z0-9_]+)\}|([a-z0-9_]+))`)
+)
+
+// Variables holding the request fields, in order of preference.
+var (
+	nginxServiceVariables = []string{"host", "http_host", "server_name"}
+	nginxMethodVariables  = []string{"request_method"}
+	nginxPathVariables    = []string{"request_uri", "uri"}
+)
+
+// NginxSource reads NGINX or Apache access logs in the common, combined or a
+// custom NGINX `log_format` format. Path may be a glob pattern matching the
+// rotated files, gzip-compressed files are decompressed. Identical requests
+// are aggregated into a single event, lines that can't be parsed are skipped.
+type NginxSource struct {
+	Path string
+
+	// Format is either `common`, `combined` or a `log_format` string, defaults
+	// to `combined`.
+	Format string
+
+	// ServiceName is used for the events when the format doesn't log the host.
+	ServiceName string
+}
+
+// nginxFormat is a compiled access log format.
+type nginxFormat struct {
+	re           *regexp.Regexp
+	serviceGroup int
+	requestGroup int
+	methodGroup  int
+	pathGroup    int
+	statusGroup  int
+}
+
+func (s *NginxSource) Stream(ctx context.Context, emit func(ApiEvent) error) error {
+	format, err := compileNginxFormat(s.Format)
+	if err != nil {
+		return err
+	}
+
+	paths := []string{s.Path}
+	if s.Path != StdinPath {
+		paths, err = filepath.Glob(s.Path)
+		if err != nil {
+			return fmt.Errorf("invalid access log path `%s`: %w", s.Path, err)
+		}
+		if len(paths) == 0 {
+			return fmt.Errorf("no access log found at `%s`", s.Path)
+		}
+	}
+
+	events := newAggregator(ctx, emit)
+	skippedLines := 0
+	for _, path := range paths {
+		skipped, err := s.readFile(ctx, path, format, events)
+		if err != nil {
+			return err
+		}
+		skippedLines += skipped
+	}
+
+	if skippedLines > 0 {
+		util.GetLogger().Warnf("skipped %d access log lines that could not be parsed as HTTP requests", skippedLines)
+	}
+	return events.flush()
+}
+
+// readFile adds the requests logged in the file to the events and returns the
+// number of lines that couldn't be parsed.
+func (s *NginxSource) readFile(ctx context.Context, path string, format *nginxFormat, events *aggregator) (int, error) {
+	input, err := openInput(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open access logs: %w", err)
+	}
+	defer input.Close()
+
+	reader, err := decompress(input)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decompress access logs `%s`: %w", path, err)
+	}
+
+	skippedLines := 0
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLineSize)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		event, ok := format.parse(line)
+		if !ok {
+			skippedLines++
+			continue
+		}
+		if event.ServiceName == "" {
+			event.ServiceName = s.ServiceName
+		}
+		if err := events.add(event); err != nil {
+			return 0, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read access logs `%s`: %w", path, err)
+	}
+	return skippedLines, nil
+}
+
+// decompress transparently decompresses gzip input.
+func decompress(input io.Reader) (io.Reader, error) {
+	reader := bufio.NewReader(input)
+	magic, err := reader.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return reader, nil
+	}
+	return gzip.NewReader(reader)
+}
+
+// compileNginxFormat turns a `log_format` string into a regular expression
+// capturing every variable.
+func compileNginxFormat(format string) (*nginxFormat, error) {
+	if format == "" {
+		format = NginxFormatCombined
+	}
+	if predefined, ok := nginxFormats[format]; ok {
+		format = predefined
+	}
+
+	groupByVariable := make(map[string]int)
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	end := 0
+	for group, loc := range nginxVariablePattern.FindAllStringSubmatchIndex(format, -1) {
+		pattern.WriteString(regexp.QuoteMeta(format[end:loc[0]]))
+		pattern.WriteString("(.*?)")
+		end = loc[1]
+
+		var variable string
+		if loc[2] != -1 {
+			variable = format[loc[2]:loc[3]] // ${variable}
+		} else {
+			variable = format[loc[4]:loc[5]]
+		}
+		if _, exists := groupByVariable[variable]; !exists {
+			groupByVariable[variable] = group + 1
+		}
+	}
+	pattern.WriteString(regexp.QuoteMeta(format[end:]))
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid access log format: %w", err)
+	}
+
+	compiled := &nginxFormat{
+		re:           re,
+		serviceGroup: findVariable(groupByVariable, nginxServiceVariables),
+		requestGroup: groupByVariable["request"],
+		methodGroup:  findVariable(groupByVariable, nginxMethodVariables),
+		pathGroup:    findVariable(groupByVariable, nginxPathVariables),
+		statusGroup:  groupByVariable["status"],
+	}
+	if compiled.requestGroup == 0 && (compiled.methodGroup == 0 || compiled.pathGroup == 0) {
+		return nil, fmt.Errorf("invalid access log format: either `$request` or the request method and URI must be logged")
+	}
+	return compiled, nil
+}
+
+func findVariable(groupByVariable map[string]int, variables []string) int {
+	for _, variable := range variables {
+		if group, ok := groupByVariable[variable]; ok {
+			return group
+		}
+	}
+	return 0
+}
+
+func (f *nginxFormat) parse(line string) (ApiEvent, bool) {
+	matches := f.re.FindStringSubmatch(line)
+	if matches == nil {
+		return ApiEvent{}, false
+	}
+
+	field := func(group int) string {
+		if group == 0 || matches[group] == "-" {
+			return ""
+		}
+		return matches[group]
+	}
+
+	method, path := field(f.methodGroup), field(f.pathGroup)
+	if method == "" || path == "" {
+		// e.g. "GET /users/1 HTTP/1.1"
+		request := strings.Fields(field(f.requestGroup))
+		if len(request) < 2 {
+			return ApiEvent{}, false
+		}
+		method, path = request[0], request[1]
+	}
+
+	status, _ := strconv.Atoi(field(f.statusGroup))
+	return ApiEvent{
+		ServiceName:   field(f.serviceGroup),
+		RequestMethod: method,
+		RequestPath:   path,
+		ResponseCode:  status,
+	}, true
+}
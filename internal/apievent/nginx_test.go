@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNginxSource_Stream_RotatedFiles(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "access.log"), []byte(
+		`10.0.0.1 - - [01/Jan/2024:00:00:01 +0000] "GET /users/1?fields=name HTTP/1.1" 200 12 "-" "curl/8.0 (x86_64)"`+"\n"+
+			`10.0.0.1 - - [01/Jan/2024:00:00:02 +0000] "\x16\x03\x01" 400 0 "-" "-"`+"\n"), 0o644))
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte(
+		`10.0.0.2 - bob [31/Dec/2023:23:59:59 +0000] "GET /users/1?fields=name HTTP/1.1" 200 12 "https://example.com/" "Mozilla/5.0"` + "\n" +
+			`10.0.0.2 - bob [31/Dec/2023:23:59:59 +0000] "DELETE /users/2 HTTP/1.1" 204 0 "-" "Mozilla/5.0"` + "\n"))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "access.log.2.gz"), compressed.Bytes(), 0o644))
+
+	events, err := collect(t, &NginxSource{Path: filepath.Join(dir, "access.log*"), ServiceName: "legacy"})
+	require.NoError(t, err)
+	assert.Equal(t, []ApiEvent{
+		{ServiceName: "legacy", RequestMethod: "GET", RequestPath: "/users/1?fields=name", ResponseCode: 200, Occurrences: 2},
+		{ServiceName: "legacy", RequestMethod: "DELETE", RequestPath: "/users/2", ResponseCode: 204, Occurrences: 1},
+	}, events)
+}
+
+func TestNginxSource_Stream_Formats(t *testing.T) {
+	tests := map[string]struct {
+		format string
+		line   string
+		want   ApiEvent
+	}{
+		"common": {
+			format: "common",
+			line:   `10.0.0.1 - - [01/Jan/2024:00:00:01 +0000] "POST /login HTTP/1.0" 302 -`,
+			want:   ApiEvent{RequestMethod: "POST", RequestPath: "/login", ResponseCode: 302, Occurrences: 1},
+		},
+		"custom": {
+			format: `${host}|$request_method|$request_uri|$status|$request_time`,
+			line:   `api.example.com|PUT|/orders/7|200|0.003`,
+			want:   ApiEvent{ServiceName: "api.example.com", RequestMethod: "PUT", RequestPath: "/orders/7", ResponseCode: 200, Occurrences: 1},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			events, err := collect(t, &NginxSource{Path: writeInput(t, "access.log", test.line+"\n"), Format: test.format})
+			require.NoError(t, err)
+			assert.Equal(t, []ApiEvent{test.want}, events)
+		})
+	}
+}
+
+func TestNginxSource_Stream_Errors(t *testing.T) {
+	_, err := collect(t, &NginxSource{Path: writeInput(t, "access.log", ""), Format: `$remote_addr $status`})
+	assert.ErrorContains(t, err, "must be logged")
+
+	_, err = collect(t, &NginxSource{Path: filepath.Join(t.TempDir(), "access.log*")})
+	assert.ErrorContains(t, err, "no access log found")
+}
@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/5gsec/api-speculator/internal/util"
+)
+
+const (
+	// OTLPTracesPath is the path the OTLP/HTTP receiver accepts traces on.
+	OTLPTracesPath = "/v1/traces"
+
+	maxOTLPRequestSize = 32 * 1024 * 1024
+)
+
+// Span attributes holding the request fields, in order of preference. The
+// deprecated HTTP semantic conventions are used as fallbacks.
+var (
+	otelMethodAttributes     = []string{"http.request.method", "http.method"}
+	otelStatusCodeAttributes = []string{"http.response.status_code", "http.status_code"}
+	otelServiceAttributes    = []string{"server.address", "net.host.name", "http.host"}
+
+	// otelRouteParameterPattern matches `:id` and `<id>` or `<int:id>` route
+	// parameters.
+	otelRouteParameterPattern = regexp.MustCompile(`^(?::([^/]+)|<(?:[^:>]+:)?([^>]+)>)$`)
+)
+
+var otlpJSONOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// OTelSource reads the HTTP server spans of OpenTelemetry traces, either from
+// an OTLP JSON export or by receiving them on an OTLP/HTTP endpoint. Identical
+// requests are aggregated into a single event.
+type OTelSource struct {
+	// Path of the OTLP JSON export, either a single export or one export per
+	// line. Optional if Listen is set.
+	Path string
+
+	// Listen is the address of the OTLP/HTTP receiver, e.g. `:4318`. The
+	// receiver is disabled if empty.
+	Listen string
+
+	// Duration the receiver receives traces for, until ctx is canceled if 0.
+	// Canceling ctx ends the receiving, the received events are still emitted.
+	Duration time.Duration
+}
+
+func (s *OTelSource) Stream(ctx context.Context, emit func(ApiEvent) error) error {
+	aggregatorCtx := ctx
+	if s.Listen != "" {
+		aggregatorCtx = context.WithoutCancel(ctx)
+	}
+	events := &otelEvents{events: newAggregator(aggregatorCtx, emit)}
+
+	if s.Path != "" {
+		if err := s.readExport(ctx, events); err != nil {
+			return err
+		}
+	}
+	if s.Listen != "" {
+		if err := s.receive(ctx, events); err != nil {
+			return err
+		}
+	}
+
+	return events.events.flush()
+}
+
+// otelEvents aggregates the events of spans received concurrently, emitting
+// them one at a time.
+type otelEvents struct {
+	mu     sync.Mutex
+	events *aggregator
+}
+
+func (e *otelEvents) addTraces(traces *tracepb.TracesData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, resourceSpans := range traces.GetResourceSpans() {
+		serviceName := stringAttribute(resourceSpans.GetResource().GetAttributes(), "service.name")
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			for _, span := range scopeSpans.GetSpans() {
+				event, ok := spanEvent(span, serviceName)
+				if !ok {
+					continue
+				}
+				if err := e.events.add(event); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *OTelSource) readExport(ctx context.Context, events *otelEvents) error {
+	input, err := openInput(s.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open OTLP export: %w", err)
+	}
+	defer input.Close()
+
+	decoder := json.NewDecoder(input)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var export json.RawMessage
+		if err := decoder.Decode(&export); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read OTLP export: %w", err)
+		}
+
+		traces := &tracepb.TracesData{}
+		if err := otlpJSONOptions.Unmarshal(export, traces); err != nil {
+			return fmt.Errorf("failed to decode OTLP export: %w", err)
+		}
+		if err := events.addTraces(traces); err != nil {
+			return err
+		}
+	}
+}
+
+// receive runs the OTLP/HTTP receiver until the duration elapsed or ctx is
+// canceled, both end the receiving normally.
+func (s *OTelSource) receive(ctx context.Context, events *otelEvents) error {
+	listener, err := net.Listen("tcp", s.Listen)
+	if err != nil {
+		return fmt.Errorf("failed to start OTLP receiver: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(OTLPTracesPath, func(w http.ResponseWriter, r *http.Request) {
+		handleOTLPTraces(w, r, events)
+	})
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	util.GetLogger().Infof("receiving OTLP traces on `%s`", listener.Addr())
+
+	var timeout <-chan time.Time
+	if s.Duration > 0 {
+		timer := time.NewTimer(s.Duration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("OTLP receiver failed: %w", err)
+	case <-ctx.Done():
+		util.GetLogger().Info("stopping OTLP receiver")
+	case <-timeout:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to stop OTLP receiver: %w", err)
+	}
+	return nil
+}
+
+func handleOTLPTraces(w http.ResponseWriter, r *http.Request, events *otelEvents) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, maxOTLPRequestSize)
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer gzipReader.Close()
+		body = gzipReader
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// ExportTraceServiceRequest is wire compatible with TracesData.
+	traces := &tracepb.TracesData{}
+	contentType := r.Header.Get("Content-Type")
+	isJSON := strings.HasPrefix(contentType, "application/json")
+	if isJSON {
+		err = otlpJSONOptions.Unmarshal(data, traces)
+	} else {
+		err = proto.Unmarshal(data, traces)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := events.addTraces(traces); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	// Empty ExportTraceServiceResponse.
+	if isJSON {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte("{}"))
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	w.WriteHeader(http.StatusOK)
+}
+
+// spanEvent converts an HTTP server span into an event.
+func spanEvent(span *tracepb.Span, serviceName string) (ApiEvent, bool) {
+	if span.GetKind() != tracepb.Span_SPAN_KIND_SERVER {
+		return ApiEvent{}, false
+	}
+	attributes := span.GetAttributes()
+
+	method := stringAttribute(attributes, otelMethodAttributes...)
+	requestPath := stringAttribute(attributes, "url.path")
+	if requestPath != "" {
+		if query := stringAttribute(attributes, "url.query"); query != "" {
+			requestPath += "?" + query
+		}
+	} else {
+		requestPath = stringAttribute(attributes, "http.target")
+	}
+	if method == "" || requestPath == "" {
+		return ApiEvent{}, false
+	}
+
+	if address := stringAttribute(attributes, otelServiceAttributes...); address != "" {
+		serviceName = address
+	}
+	statusCode, _ := strconv.Atoi(stringAttribute(attributes, otelStatusCodeAttributes...))
+
+	return ApiEvent{
+		ServiceName:   serviceName,
+		RequestMethod: method,
+		RequestPath:   requestPath,
+		RouteTemplate: normalizeRouteTemplate(stringAttribute(attributes, "http.route")),
+		ResponseCode:  statusCode,
+	}, true
+}
+
+// stringAttribute returns the value of the first of the given attributes that
+// is set, formatted as a string.
+func stringAttribute(attributes []*commonpb.KeyValue, keys ...string) string {
+	for _, key := range keys {
+		for _, attribute := range attributes {
+			if attribute.GetKey() != key {
+				continue
+			}
+			switch value := attribute.GetValue().GetValue().(type) {
+			case *commonpb.AnyValue_StringValue:
+				return value.StringValue
+			case *commonpb.AnyValue_IntValue:
+				return strconv.FormatInt(value.IntValue, 10)
+			}
+		}
+	}
+	return ""
+}
+
+// normalizeRouteTemplate rewrites the path parameters of a server framework
+// route, e.g. `/users/:id` or `/users/<int:id>`, into the OpenAPI syntax.
+func normalizeRouteTemplate(route string) string {
+	segments := strings.Split(route, "/")
+	for idx, segment := range segments {
+		matches := otelRouteParameterPattern.FindStringSubmatch(segment)
+		if matches == nil {
+			continue
+		}
+		segments[idx] = util.ParamPrefix + matches[1] + matches[2] + util.ParamSuffix
+	}
+	return strings.Join(segments, "/")
+}
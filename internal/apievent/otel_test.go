@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const testOTLPExport = `{"resourceSpans":[{
+  "resource":{"attributes":[{"key":"service.name","value":{"stringValue":"users"}}]},
+  "scopeSpans":[{"spans":[
+    {"traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174","name":"GET /users/{id}","kind":2,"attributes":[
+      {"key":"http.request.method","value":{"stringValue":"GET"}},
+      {"key":"url.path","value":{"stringValue":"/users/me"}},
+      {"key":"url.query","value":{"stringValue":"fields=name"}},
+      {"key":"http.route","value":{"stringValue":"/users/:id"}},
+      {"key":"http.response.status_code","value":{"intValue":"200"}}]},
+    {"name":"SELECT users","kind":"SPAN_KIND_CLIENT","attributes":[
+      {"key":"db.system","value":{"stringValue":"postgresql"}}]}
+  ]}]}]}
+{"resourceSpans":[{"scopeSpans":[{"spans":[
+  {"name":"POST","kind":"SPAN_KIND_SERVER","attributes":[
+    {"key":"http.method","value":{"stringValue":"POST"}},
+    {"key":"http.target","value":{"stringValue":"/orders"}},
+    {"key":"net.host.name","value":{"stringValue":"orders.example.com"}},
+    {"key":"http.status_code","value":{"intValue":201}}]}
+]}]}]}
+`
+
+func TestOTelSource_Stream_Export(t *testing.T) {
+	events, err := collect(t, &OTelSource{Path: writeInput(t, "traces.json", testOTLPExport)})
+	require.NoError(t, err)
+	assert.Equal(t, []ApiEvent{
+		{ServiceName: "users", RequestMethod: "GET", RequestPath: "/users/me?fields=name", RouteTemplate: "/users/{id}", ResponseCode: 200, Occurrences: 1},
+		{ServiceName: "orders.example.com", RequestMethod: "POST", RequestPath: "/orders", ResponseCode: 201, Occurrences: 1},
+	}, events)
+}
+
+func TestOTelSource_Stream_Receiver(t *testing.T) {
+	traces, err := proto.Marshal(&tracepb.TracesData{ResourceSpans: []*tracepb.ResourceSpans{{
+		ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{
+			Kind: tracepb.Span_SPAN_KIND_SERVER,
+			Attributes: []*commonpb.KeyValue{
+				{Key: "http.request.method", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "DELETE"}}},
+				{Key: "url.path", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "/users/1"}}},
+				{Key: "server.address", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "users.example.com"}}},
+				{Key: "http.response.status_code", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 204}}},
+			},
+		}}}},
+	}}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		duration time.Duration
+		// cancel stops the receiver once the traces were received if set.
+		cancel bool
+	}{
+		{name: "duration elapsed", duration: time.Second},
+		{name: "canceled", cancel: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			address := listener.Addr().String()
+			require.NoError(t, listener.Close())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var events []ApiEvent
+			done := make(chan error, 1)
+			go func() {
+				done <- (&OTelSource{Listen: address, Duration: tt.duration}).Stream(ctx, func(event ApiEvent) error {
+					events = append(events, event)
+					return nil
+				})
+			}()
+
+			require.Eventually(t, func() bool {
+				response, err := http.Post("http://"+address+OTLPTracesPath, "application/x-protobuf", bytes.NewReader(traces))
+				if err != nil {
+					return false
+				}
+				defer response.Body.Close()
+				return response.StatusCode == http.StatusOK
+			}, time.Second/2, 10*time.Millisecond)
+			if tt.cancel {
+				cancel()
+			}
+
+			require.NoError(t, <-done)
+			assert.Equal(t, []ApiEvent{
+				{ServiceName: "users.example.com", RequestMethod: "DELETE", RequestPath: "/users/1", ResponseCode: 204, Occurrences: 1},
+			}, events)
+		})
+	}
+}
+
+func TestNormalizeRouteTemplate(t *testing.T) {
+	assert.Equal(t, "/users/{id}/orders/{orderId}", normalizeRouteTemplate("/users/:id/orders/{orderId}"))
+	assert.Equal(t, "/files/{name}", normalizeRouteTemplate("/files/<path:name>"))
+	assert.Equal(t, "/items/{id}", normalizeRouteTemplate("/items/<id>"))
+	assert.Equal(t, "", normalizeRouteTemplate(""))
+}
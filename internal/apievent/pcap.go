@@ -0,0 +1,302 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/google/gopacket/tcpassembly"
+
+	"github.com/5gsec/api-speculator/internal/util"
+)
+
+const (
+	// pcapIdleTimeout is the capture time after which idle connections are
+	// considered closed.
+	pcapIdleTimeout = 2 * time.Minute
+
+	pcapFlushInterval = 10000
+)
+
+var pcapngMagic = []byte{0x0a, 0x0d, 0x0d, 0x0a}
+
+// PcapSource reads plaintext HTTP/1.x traffic from a pcap or pcapng capture.
+// TCP streams are reassembled and their requests paired with their responses,
+// the Host header is used as the service name. Identical requests are
+// aggregated into a single event.
+type PcapSource struct {
+	Path string
+}
+
+// packetReader is implemented by both the pcap and the pcapng readers.
+type packetReader interface {
+	gopacket.PacketDataSource
+	LinkType() layers.LinkType
+}
+
+func (s *PcapSource) Stream(ctx context.Context, emit func(ApiEvent) error) error {
+	input, err := openInput(s.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open packet capture: %w", err)
+	}
+	defer input.Close()
+
+	reader, err := newPacketReader(input)
+	if err != nil {
+		return fmt.Errorf("failed to read packet capture: %w", err)
+	}
+
+	factory := &httpStreamFactory{
+		connections: make(map[string]*httpConnection),
+		events:      newAggregator(ctx, emit),
+	}
+	assembler := tcpassembly.NewAssembler(tcpassembly.NewStreamPool(factory))
+
+	packets := gopacket.NewPacketSource(reader, reader.LinkType())
+	packets.DecodeOptions = gopacket.DecodeOptions{Lazy: true, NoCopy: true}
+	count := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		packet, err := packets.NextPacket()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read packet capture: %w", err)
+		}
+
+		network := packet.NetworkLayer()
+		tcp, ok := packet.TransportLayer().(*layers.TCP)
+		if network == nil || !ok {
+			continue
+		}
+		timestamp := packet.Metadata().Timestamp
+		assembler.AssembleWithTimestamp(network.NetworkFlow(), tcp, timestamp)
+		if factory.err != nil {
+			return factory.err
+		}
+
+		count++
+		if count%pcapFlushInterval == 0 {
+			assembler.FlushOlderThan(timestamp.Add(-pcapIdleTimeout))
+		}
+	}
+	assembler.FlushAll()
+	factory.parseRemaining()
+	if factory.err != nil {
+		return factory.err
+	}
+
+	if factory.skippedConnections > 0 {
+		util.GetLogger().Warnf("skipped %d TCP connections that could not be parsed as HTTP/1.x", factory.skippedConnections)
+	}
+	return factory.events.flush()
+}
+
+func newPacketReader(input io.Reader) (packetReader, error) {
+	buffered := bufio.NewReader(input)
+	magic, err := buffered.Peek(len(pcapngMagic))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(magic, pcapngMagic) {
+		return pcapgo.NewNgReader(buffered, pcapgo.DefaultNgReaderOptions)
+	}
+	return pcapgo.NewReader(buffered)
+}
+
+// httpStreamFactory pairs the two directions of the TCP connections and parses
+// their HTTP exchanges once both directions are complete. The assembler calls
+// it from a single goroutine.
+type httpStreamFactory struct {
+	connections        map[string]*httpConnection
+	events             *aggregator
+	skippedConnections int
+	// err is the first error emitting the events.
+	err error
+}
+
+// httpConnection holds the reassembled data of both directions of a TCP
+// connection.
+type httpConnection struct {
+	key     string
+	streams []*httpStream
+}
+
+// httpStream buffers the data of one direction of a TCP connection, up to the
+// first gap in the capture.
+type httpStream struct {
+	factory    *httpStreamFactory
+	connection *httpConnection
+	data       bytes.Buffer
+	truncated  bool
+	complete   bool
+}
+
+func (f *httpStreamFactory) New(netFlow, tcpFlow gopacket.Flow) tcpassembly.Stream {
+	src := netFlow.Src().String() + ":" + tcpFlow.Src().String()
+	dst := netFlow.Dst().String() + ":" + tcpFlow.Dst().String()
+	key := src + "-" + dst
+	if dst < src {
+		key = dst + "-" + src
+	}
+
+	connection, exists := f.connections[key]
+	if !exists {
+		connection = &httpConnection{key: key}
+		f.connections[key] = connection
+	}
+	stream := &httpStream{factory: f, connection: connection}
+	connection.streams = append(connection.streams, stream)
+	return stream
+}
+
+func (s *httpStream) Reassembled(reassemblies []tcpassembly.Reassembly) {
+	for _, reassembly := range reassemblies {
+		if s.truncated {
+			return
+		}
+		if reassembly.Skip > 0 {
+			// Packets are missing, the rest of the stream can't be parsed.
+			s.truncated = true
+			return
+		}
+		s.data.Write(reassembly.Bytes)
+	}
+}
+
+func (s *httpStream) ReassemblyComplete() {
+	s.complete = true
+	for _, stream := range s.connection.streams {
+		if !stream.complete {
+			return
+		}
+	}
+	if len(s.connection.streams) == 2 {
+		s.factory.parse(s.connection)
+	}
+}
+
+// parseRemaining parses the connections only one direction was captured of.
+func (f *httpStreamFactory) parseRemaining() {
+	for _, connection := range f.connections {
+		f.parse(connection)
+	}
+}
+
+func (f *httpStreamFactory) parse(connection *httpConnection) {
+	delete(f.connections, connection.key)
+
+	// The directions are told apart by their data, the requests sent on a
+	// connection left unanswered are still parsed.
+	var requestData, responseData []byte
+	for _, stream := range connection.streams {
+		data := stream.data.Bytes()
+		switch {
+		case len(data) == 0:
+		case bytes.HasPrefix(data, []byte("HTTP/")):
+			responseData = data
+		default:
+			requestData = data
+		}
+	}
+	if len(requestData) == 0 {
+		if len(responseData) > 0 {
+			f.skippedConnections++
+		}
+		return
+	}
+
+	events, err := parseHTTPExchanges(requestData, responseData)
+	if len(events) == 0 && err != nil {
+		f.skippedConnections++
+		return
+	}
+	for _, event := range events {
+		if f.err != nil {
+			return
+		}
+		f.err = f.events.add(event)
+	}
+}
+
+// parseHTTPExchanges parses the requests sent on a connection and pairs them
+// with the responses in order, the requests without a response have no
+// response code. The events parsed before an error are returned along with it.
+func parseHTTPExchanges(requestData, responseData []byte) ([]ApiEvent, error) {
+	var events []ApiEvent
+	var requests []*http.Request
+
+	requestReader := bufio.NewReader(bytes.NewReader(requestData))
+	var parseErr error
+	for {
+		request, err := http.ReadRequest(requestReader)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			parseErr = err
+			break
+		}
+		if _, err := io.Copy(io.Discard, request.Body); err != nil {
+			parseErr = err
+			break
+		}
+		requests = append(requests, request)
+
+		requestPath := request.RequestURI
+		if !strings.HasPrefix(requestPath, "/") {
+			// Absolute form, sent to proxies.
+			requestPath = request.URL.RequestURI()
+		}
+		events = append(events, ApiEvent{
+			ServiceName:   request.Host,
+			RequestMethod: request.Method,
+			RequestPath:   requestPath,
+		})
+	}
+
+	responseReader := bufio.NewReader(bytes.NewReader(responseData))
+	for idx, request := range requests {
+		response, err := readFinalResponse(responseReader, request)
+		if err != nil {
+			break
+		}
+		_, err = io.Copy(io.Discard, response.Body)
+		events[idx].ResponseCode = response.StatusCode
+		events[idx].ContentType = response.Header.Get("Content-Type")
+		if err != nil || response.StatusCode == http.StatusSwitchingProtocols {
+			break
+		}
+	}
+
+	return events, parseErr
+}
+
+// readFinalResponse reads the response to the request, skipping informational
+// responses such as `100 Continue`.
+func readFinalResponse(reader *bufio.Reader, request *http.Request) (*http.Response, error) {
+	for {
+		// The request is needed to parse the responses to HEAD requests.
+		response, err := http.ReadResponse(reader, request)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode >= 200 || response.StatusCode == http.StatusSwitchingProtocols {
+			return response, nil
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCapture writes TCP segments of a connection to a pcap file.
+type testCapture struct {
+	t          *testing.T
+	writer     *pcapgo.Writer
+	timestamp  time.Time
+	clientPort layers.TCPPort
+	seq        map[bool]uint32
+}
+
+func newTestCapture(t *testing.T, file *os.File) *testCapture {
+	writer := pcapgo.NewWriter(file)
+	require.NoError(t, writer.WriteFileHeader(65536, layers.LinkTypeEthernet))
+	return &testCapture{
+		t:          t,
+		writer:     writer,
+		timestamp:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		clientPort: 40000,
+		seq:        map[bool]uint32{true: 1000, false: 5000},
+	}
+}
+
+// reconnect makes the next segments belong to a new connection from the given
+// client port.
+func (c *testCapture) reconnect(clientPort layers.TCPPort) {
+	c.clientPort = clientPort
+	c.seq = map[bool]uint32{true: 1000, false: 5000}
+}
+
+// send writes a segment sent by the client or by the server.
+func (c *testCapture) send(fromClient bool, payload string, syn, fin bool) {
+	clientIP, serverIP := net.IP{10, 0, 0, 1}, net.IP{10, 0, 0, 2}
+	clientPort, serverPort := c.clientPort, layers.TCPPort(80)
+
+	ip := &layers.IPv4{Version: 4, TTL: 64, Protocol: layers.IPProtocolTCP, SrcIP: serverIP, DstIP: clientIP}
+	tcp := &layers.TCP{SrcPort: serverPort, DstPort: clientPort, Seq: c.seq[fromClient], SYN: syn, FIN: fin, ACK: true, Window: 65535}
+	if fromClient {
+		ip.SrcIP, ip.DstIP = clientIP, serverIP
+		tcp.SrcPort, tcp.DstPort = clientPort, serverPort
+	}
+	require.NoError(c.t, tcp.SetNetworkLayerForChecksum(ip))
+
+	buffer := gopacket.NewSerializeBuffer()
+	require.NoError(c.t, gopacket.SerializeLayers(buffer, gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true},
+		&layers.Ethernet{SrcMAC: net.HardwareAddr{0, 0, 0, 0, 0, 1}, DstMAC: net.HardwareAddr{0, 0, 0, 0, 0, 2}, EthernetType: layers.EthernetTypeIPv4},
+		ip, tcp, gopacket.Payload(payload)))
+
+	c.timestamp = c.timestamp.Add(time.Millisecond)
+	data := buffer.Bytes()
+	require.NoError(c.t, c.writer.WritePacket(gopacket.CaptureInfo{Timestamp: c.timestamp, CaptureLength: len(data), Length: len(data)}, data))
+
+	c.seq[fromClient] += uint32(len(payload))
+	if syn || fin {
+		c.seq[fromClient]++
+	}
+}
+
+func TestPcapSource_Stream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.pcap")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+
+	capture := newTestCapture(t, file)
+	capture.send(true, "", true, false)
+	capture.send(false, "", true, false)
+	capture.send(true, "GET /users/1?fields=name HTTP/1.1\r\nHost: api.example.com\r\n\r\n", false, false)
+	capture.send(false, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\n{}", false, false)
+	// The body is split across segments.
+	capture.send(true, "POST /users HTTP/1.1\r\nHost: api.example.com\r\nContent-Length: 12\r\n\r\n{\"name\":", false, false)
+	capture.send(true, "\"a\"}", false, false)
+	capture.send(false, "HTTP/1.1 100 Continue\r\n\r\nHTTP/1.1 201 Created\r\nTransfer-Encoding: chunked\r\n\r\n2\r\n{}\r\n0\r\n\r\n", false, false)
+	capture.send(true, "HEAD /users/1 HTTP/1.1\r\nHost: api.example.com\r\n\r\n", false, false)
+	capture.send(false, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\n", false, false)
+	capture.send(true, "GET /users/1?fields=name HTTP/1.1\r\nHost: api.example.com\r\n\r\n", false, false)
+	capture.send(false, "HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\n{}", false, false)
+	capture.send(true, "", false, true)
+	capture.send(false, "", false, true)
+	// A request left unanswered, the server direction has no data.
+	capture.reconnect(40001)
+	capture.send(true, "", true, false)
+	capture.send(false, "", true, false)
+	capture.send(true, "DELETE /admin/users/1 HTTP/1.1\r\nHost: api.example.com\r\n\r\n", false, false)
+	require.NoError(t, file.Close())
+
+	events, err := collect(t, &PcapSource{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, []ApiEvent{
+		{ServiceName: "api.example.com", RequestMethod: "GET", RequestPath: "/users/1?fields=name", ResponseCode: 200, Occurrences: 2},
+		{ServiceName: "api.example.com", RequestMethod: "POST", RequestPath: "/users", ResponseCode: 201, Occurrences: 1},
+		{ServiceName: "api.example.com", RequestMethod: "HEAD", RequestPath: "/users/1", ResponseCode: 200, Occurrences: 1},
+		{ServiceName: "api.example.com", RequestMethod: "DELETE", RequestPath: "/admin/users/1", Occurrences: 1},
+	}, events)
+}
+
+func TestPcapSource_Stream_Invalid(t *testing.T) {
+	_, err := collect(t, &PcapSource{Path: writeInput(t, "capture.pcap", "not a capture")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apievent
+
+import (
+	"context"
+	"io"
+	"os"
+)
+
+// EventSource streams the API events observed in some traffic.
+type EventSource interface {
+	// Stream calls emit for every event of the source until the source is
+	// exhausted, ctx is canceled or emit returns an error.
+	Stream(ctx context.Context, emit func(ApiEvent) error) error
+}
+
+// StdinPath is the input path standing for the standard input.
+const StdinPath = "-"
+
+// openInput opens the file at the given path, or the standard input.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == StdinPath {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
@@ -0,0 +1,287 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apispec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/5gsec/api-speculator/internal/config"
+	"github.com/5gsec/api-speculator/internal/util"
+)
+
+const defaultDownloadTimeout = 30 * time.Second
+
+// Downloader fetches specs over HTTP(S), optionally authenticated and cached
+// on disk.
+type Downloader struct {
+	client *http.Client
+	// header holds the configured headers and credentials, only sent to origin.
+	header http.Header
+	// origin is the scheme and host of the spec URL.
+	origin   *url.URL
+	cacheDir string
+}
+
+// cacheEntry is the metadata stored next to a cached spec.
+type cacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// NewDownloader creates a Downloader for the spec at specURL, reading the
+// configured secrets and TLS files.
+func NewDownloader(specURL string, cfg config.SpecDownload) (*Downloader, error) {
+	origin, err := url.Parse(specURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spec URL: %w", err)
+	}
+
+	header := make(http.Header)
+	for key, value := range cfg.Headers {
+		header.Set(key, value)
+	}
+
+	if cfg.BearerToken.IsSet() {
+		token, err := cfg.BearerToken.Value()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bearer token: %w", err)
+		}
+		header.Set("Authorization", "Bearer "+token)
+	}
+	if cfg.BasicAuth.Username != "" {
+		password, err := cfg.BasicAuth.Password.Value()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read basic auth password: %w", err)
+		}
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(cfg.BasicAuth.Username, password)
+		header.Set("Authorization", req.Header.Get("Authorization"))
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultDownloadTimeout
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &Downloader{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+		header:   header,
+		origin:   origin,
+		cacheDir: cfg.CacheDir,
+	}, nil
+}
+
+func newTLSConfig(cfg config.SpecDownload) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAFile != "" {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		caBundle, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no certificate found in CA bundle `%s`", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Get sends a GET request, authenticated if it is sent to the host of the
+// spec. It can be used by libopenapi to resolve remote references, which are
+// cached like the spec if caching is enabled.
+func (d *Downloader) Get(url string) (*http.Response, error) {
+	if d.cacheDir == "" {
+		return d.get(url, nil)
+	}
+
+	data, err := d.Download(url)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+	}, nil
+}
+
+func (d *Downloader) get(url string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	// The credentials of the spec host must not leak to the hosts of remote
+	// references.
+	if d.isOrigin(req.URL) {
+		for key, values := range d.header {
+			req.Header[key] = values
+		}
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	return d.client.Do(req)
+}
+
+// isOrigin reports whether the URL has the scheme and host of the spec URL.
+func (d *Downloader) isOrigin(u *url.URL) bool {
+	return strings.EqualFold(u.Scheme, d.origin.Scheme) && strings.EqualFold(u.Host, d.origin.Host)
+}
+
+// Download fetches the spec at the given URL. If caching is enabled, the
+// cached copy is revalidated and returned when it is still up-to-date or when
+// the spec cannot be downloaded.
+func (d *Downloader) Download(url string) ([]byte, error) {
+	if d.cacheDir == "" {
+		return d.download(url)
+	}
+
+	cached, entry, cacheErr := d.readCache(url)
+	header := make(http.Header)
+	if cacheErr == nil {
+		if entry.ETag != "" {
+			header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	response, err := d.get(url, header)
+	if err == nil {
+		defer response.Body.Close()
+	}
+
+	switch {
+	case err == nil && response.StatusCode == http.StatusNotModified && cacheErr == nil:
+		util.GetLogger().Debugf("using cached copy of `%s` spec, not modified", url)
+		return cached, nil
+	case err == nil && response.StatusCode == http.StatusOK:
+		data, readErr := io.ReadAll(response.Body)
+		if readErr == nil {
+			if err := d.writeCache(url, data, response.Header); err != nil {
+				util.GetLogger().Warnf("failed to cache `%s` spec: %v", url, err)
+			}
+			return data, nil
+		}
+		err = readErr
+	case err == nil:
+		err = fmt.Errorf("unexpected response status `%s`", response.Status)
+	}
+
+	if cacheErr != nil {
+		return nil, err
+	}
+	util.GetLogger().Warnf("failed to download `%s` spec, falling back to the cached copy: %v", url, err)
+	return cached, nil
+}
+
+func (d *Downloader) download(url string) ([]byte, error) {
+	response, err := d.get(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status `%s`", response.Status)
+	}
+
+	return io.ReadAll(response.Body)
+}
+
+// cachePaths returns the paths of the cached spec and of its metadata.
+func (d *Downloader) cachePaths(url string) (string, string) {
+	sum := sha256.Sum256([]byte(url))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(d.cacheDir, key+".spec"), filepath.Join(d.cacheDir, key+".json")
+}
+
+func (d *Downloader) readCache(url string) ([]byte, cacheEntry, error) {
+	specPath, entryPath := d.cachePaths(url)
+
+	var entry cacheEntry
+	entryBytes, err := os.ReadFile(entryPath)
+	if err != nil {
+		return nil, entry, err
+	}
+	if err := json.Unmarshal(entryBytes, &entry); err != nil {
+		return nil, entry, err
+	}
+	if entry.URL != url {
+		return nil, entry, errors.New("cache entry doesn't match the URL")
+	}
+
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		return nil, entry, err
+	}
+	return data, entry, nil
+}
+
+func (d *Downloader) writeCache(url string, data []byte, header http.Header) error {
+	if err := os.MkdirAll(d.cacheDir, 0o755); err != nil {
+		return err
+	}
+
+	specPath, entryPath := d.cachePaths(url)
+	entryBytes, err := json.Marshal(cacheEntry{
+		URL:          url,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(specPath, data, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(entryPath, entryBytes, 0o644)
+}
@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apispec
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/5gsec/api-speculator/internal/config"
+)
+
+const downloadedSpec = "openapi: 3.0.3"
+
+func TestDownloader_Download(t *testing.T) {
+	t.Setenv("SPEC_TOKEN", "s3cr3t")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" || r.Header.Get("X-Tenant") != "acme" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/openapi.yaml" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(downloadedSpec))
+	}))
+	defer server.Close()
+
+	downloader, err := NewDownloader(server.URL+"/openapi.yaml", config.SpecDownload{
+		Headers:     map[string]string{"x-tenant": "acme"},
+		BearerToken: config.Secret{Env: "SPEC_TOKEN"},
+	})
+	require.NoError(t, err)
+
+	specBytes, err := downloader.Download(server.URL + "/openapi.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, downloadedSpec, string(specBytes))
+
+	_, err = downloader.Download(server.URL + "/missing.yaml")
+	assert.ErrorContains(t, err, "404")
+
+	downloader, err = NewDownloader(server.URL+"/openapi.yaml", config.SpecDownload{})
+	require.NoError(t, err)
+	_, err = downloader.Download(server.URL + "/openapi.yaml")
+	assert.ErrorContains(t, err, "401")
+}
+
+func TestDownloader_Download_BasicAuthAndCustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "speculator" || password != "pa55" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(downloadedSpec))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	caBundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caBundle, 0o644))
+	passwordFile := filepath.Join(dir, "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("pa55\n"), 0o600))
+
+	downloader, err := NewDownloader(server.URL+"/openapi.yaml", config.SpecDownload{
+		BasicAuth: config.BasicAuth{Username: "speculator", Password: config.Secret{File: passwordFile}},
+		CAFile:    caFile,
+	})
+	require.NoError(t, err)
+
+	specBytes, err := downloader.Download(server.URL + "/openapi.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, downloadedSpec, string(specBytes))
+
+	// Without the custom CA the server certificate isn't trusted.
+	downloader, err = NewDownloader(server.URL+"/openapi.yaml", config.SpecDownload{
+		BasicAuth: config.BasicAuth{Username: "speculator", Password: config.Secret{File: passwordFile}},
+	})
+	require.NoError(t, err)
+	_, err = downloader.Download(server.URL + "/openapi.yaml")
+	assert.Error(t, err)
+}
+
+func TestDownloader_Download_Cache(t *testing.T) {
+	const etag = `"v1"`
+	requests := 0
+	notModified := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(downloadedSpec))
+	}))
+
+	specURL := server.URL + "/openapi.yaml"
+	downloader, err := NewDownloader(specURL, config.SpecDownload{CacheDir: t.TempDir()})
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		specBytes, err := downloader.Download(specURL)
+		require.NoError(t, err)
+		assert.Equal(t, downloadedSpec, string(specBytes))
+	}
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 1, notModified)
+
+	// The cached copy is used when the server is down.
+	server.Close()
+	specBytes, err := downloader.Download(specURL)
+	require.NoError(t, err)
+	assert.Equal(t, downloadedSpec, string(specBytes))
+
+	// But there is nothing to fall back to for other URLs.
+	_, err = downloader.Download(server.URL + "/other.yaml")
+	assert.Error(t, err)
+}
+
+func TestNewDownloader_MissingSecret(t *testing.T) {
+	_, err := NewDownloader("https://portal.example.com/openapi.yaml", config.SpecDownload{BearerToken: config.Secret{Env: "SPECULATOR_MISSING_TOKEN"}})
+	assert.ErrorContains(t, err, "SPECULATOR_MISSING_TOKEN")
+}
+
+func TestDownloader_Get_CrossHostReference(t *testing.T) {
+	var refHeaders http.Header
+	refServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		refHeaders = r.Header.Clone()
+		_, _ = w.Write([]byte("type: object"))
+	}))
+	defer refServer.Close()
+
+	var specHeaders http.Header
+	specServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		specHeaders = r.Header.Clone()
+		_, _ = w.Write([]byte("type: object"))
+	}))
+	defer specServer.Close()
+
+	t.Setenv("SPEC_TOKEN", "s3cr3t")
+	downloader, err := NewDownloader(specServer.URL+"/openapi.yaml", config.SpecDownload{
+		Headers:     map[string]string{"x-tenant": "acme"},
+		BearerToken: config.Secret{Env: "SPEC_TOKEN"},
+	})
+	require.NoError(t, err)
+
+	response, err := downloader.Get(specServer.URL + "/schemas/user.yaml")
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Equal(t, "Bearer s3cr3t", specHeaders.Get("Authorization"))
+	assert.Equal(t, "acme", specHeaders.Get("X-Tenant"))
+
+	response, err = downloader.Get(refServer.URL + "/schemas/user.yaml")
+	require.NoError(t, err)
+	_ = response.Body.Close()
+	assert.Empty(t, refHeaders.Get("Authorization"))
+	assert.Empty(t, refHeaders.Get("X-Tenant"))
+}
+
+func TestDownloader_Get_CachedReference(t *testing.T) {
+	const ref = "type: object"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(ref))
+	}))
+
+	downloader, err := NewDownloader(server.URL+"/openapi.yaml", config.SpecDownload{CacheDir: t.TempDir()})
+	require.NoError(t, err)
+	refURL := server.URL + "/schemas/user.yaml"
+
+	response, err := downloader.Get(refURL)
+	require.NoError(t, err)
+	_ = response.Body.Close()
+
+	// The cached reference is used when the server is down.
+	server.Close()
+	response, err = downloader.Get(refURL)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	refBytes, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.Equal(t, ref, string(refBytes))
+}
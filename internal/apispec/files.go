@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apispec
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var specFileExtensions = []string{".yaml", ".yml", ".json"}
+
+// FindSpecFiles expands a spec location into the files it refers to. The
+// location is either a single file, a directory which is walked recursively, or
+// a glob pattern. The second return value reports whether the location was
+// expanded, in which case the returned files may include fragments that are
+// not root documents.
+func FindSpecFiles(location string) ([]string, bool, error) {
+	if strings.ContainsAny(location, "*?[") {
+		matches, err := filepath.Glob(location)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid spec glob pattern `%s`: %w", location, err)
+		}
+		if len(matches) == 0 {
+			return nil, false, fmt.Errorf("no spec file matches `%s`", location)
+		}
+		sort.Strings(matches)
+		return matches, true, nil
+	}
+
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, false, err
+	}
+	if !info.IsDir() {
+		return []string{location}, false, nil
+	}
+
+	var specFiles []string
+	err = filepath.WalkDir(location, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isSpecFile(path) {
+			specFiles = append(specFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to walk spec directory `%s`: %w", location, err)
+	}
+	if len(specFiles) == 0 {
+		return nil, false, fmt.Errorf("no spec file found in `%s`", location)
+	}
+	return specFiles, true, nil
+}
+
+func isSpecFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, specFileExtension := range specFileExtensions {
+		if ext == specFileExtension {
+			return true
+		}
+	}
+	return false
+}
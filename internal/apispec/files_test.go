@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apispec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+const (
+	rootSpecWithRef = `openapi: 3.0.3
+info:
+  title: test
+  version: 1.0.0
+paths:
+  /users/{id}:
+    get:
+      parameters:
+        - $ref: "./components/params.yaml#/id"
+      responses:
+        "200":
+          description: ok
+`
+	paramsFragment = `id:
+  name: id
+  in: path
+  required: true
+  schema:
+    type: integer
+`
+)
+
+func writeSpecFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		filePath := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0o755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestFindSpecFiles(t *testing.T) {
+	dir := writeSpecFiles(t, map[string]string{
+		"users.yaml":              rootSpecWithRef,
+		"components/params.yaml":  paramsFragment,
+		"orders.json":             "{}",
+		"README.md":               "not a spec",
+		"components/extra.yml":    "{}",
+		"components/nested/x.txt": "not a spec",
+	})
+
+	tests := []struct {
+		name         string
+		location     string
+		wantFiles    []string
+		wantExpanded bool
+		wantErr      bool
+	}{
+		{
+			name:      "single file",
+			location:  filepath.Join(dir, "users.yaml"),
+			wantFiles: []string{filepath.Join(dir, "users.yaml")},
+		},
+		{
+			name:     "directory",
+			location: dir,
+			wantFiles: []string{
+				filepath.Join(dir, "components/extra.yml"),
+				filepath.Join(dir, "components/params.yaml"),
+				filepath.Join(dir, "orders.json"),
+				filepath.Join(dir, "users.yaml"),
+			},
+			wantExpanded: true,
+		},
+		{
+			name:         "glob",
+			location:     filepath.Join(dir, "*.yaml"),
+			wantFiles:    []string{filepath.Join(dir, "users.yaml")},
+			wantExpanded: true,
+		},
+		{
+			name:     "glob without matches",
+			location: filepath.Join(dir, "*.yamlx"),
+			wantErr:  true,
+		},
+		{
+			name:     "missing file",
+			location: filepath.Join(dir, "missing.yaml"),
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, expanded, err := FindSpecFiles(tt.location)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFiles, files)
+			assert.Equal(t, tt.wantExpanded, expanded)
+		})
+	}
+}
+
+func TestIsRootDocument(t *testing.T) {
+	tests := []struct {
+		name      string
+		specBytes string
+		want      bool
+		wantErr   bool
+	}{
+		{name: "openapi", specBytes: rootSpecWithRef, want: true},
+		{name: "swagger", specBytes: `{"swagger": "2.0"}`, want: true},
+		{name: "fragment", specBytes: paramsFragment},
+		{name: "malformed", specBytes: "not: [valid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IsRootDocument([]byte(tt.specBytes))
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBuildInventory_RelativeRef(t *testing.T) {
+	dir := writeSpecFiles(t, map[string]string{
+		"users.yaml":             rootSpecWithRef,
+		"components/params.yaml": paramsFragment,
+	})
+	specFile := filepath.Join(dir, "users.yaml")
+
+	inv, err := BuildInventory([]byte(rootSpecWithRef), specFile, nil)
+	require.NoError(t, err)
+	require.Len(t, inv.Operations(), 1)
+	assert.Equal(t, []inventory.Param{
+		{Name: "id", In: "path", Required: true, Schema: inventory.Schema{Type: "integer"}},
+	}, inv.Operations()[0].Params)
+}
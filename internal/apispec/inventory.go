@@ -0,0 +1,298 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apispec
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel/high/base"
+	v2 "github.com/pb33f/libopenapi/datamodel/high/v2"
+	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/orderedmap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+const sunsetExtension = "x-sunset"
+
+func inventoryFromV3Model(model *libopenapi.DocumentModel[v3.Document], source string) *inventory.Inventory {
+	inv := &inventory.Inventory{Source: source, Version: model.Model.Version}
+	if model.Model.Paths == nil {
+		return inv
+	}
+
+	for pathItems := model.Model.Paths.PathItems.First(); pathItems != nil; pathItems = pathItems.Next() {
+		servers := pathItems.Value().Servers
+		if len(servers) == 0 {
+			servers = model.Model.Servers
+		}
+		pathItem := &inventory.PathItem{
+			PathTemplate: pathItems.Key(),
+			BasePaths:    serverBasePaths(servers),
+		}
+		for operations := pathItems.Value().GetOperations().First(); operations != nil; operations = operations.Next() {
+			operation := operations.Value()
+
+			security := operation.Security
+			if security == nil {
+				security = model.Model.Security
+			}
+			var responseCodes []string
+			if operation.Responses != nil {
+				responseCodes = responseCodesOf(operation.Responses.Codes, operation.Responses.Default != nil)
+			}
+
+			pathItem.Operations = append(pathItem.Operations, &inventory.Operation{
+				Method:        strings.ToUpper(operations.Key()),
+				PathTemplate:  pathItem.PathTemplate,
+				Params:        v3Params(pathItems.Value().Parameters, operation.Parameters),
+				Deprecated:    operation.Deprecated != nil && *operation.Deprecated,
+				Sunset:        sunsetOf(operation.Extensions),
+				Security:      securitySchemeNames(security),
+				ResponseCodes: responseCodes,
+				Source:        source,
+			})
+		}
+		inv.PathItems = append(inv.PathItems, pathItem)
+	}
+	return inv
+}
+
+func inventoryFromV2Model(model *libopenapi.DocumentModel[v2.Swagger], source string) *inventory.Inventory {
+	inv := &inventory.Inventory{Source: source, Version: model.Model.Swagger}
+	if model.Model.Paths == nil {
+		return inv
+	}
+
+	var basePaths []string
+	if basePath := strings.TrimSuffix(model.Model.BasePath, "/"); basePath != "" {
+		basePaths = []string{basePath}
+	}
+
+	for pathItems := model.Model.Paths.PathItems.First(); pathItems != nil; pathItems = pathItems.Next() {
+		pathItem := &inventory.PathItem{
+			PathTemplate: pathItems.Key(),
+			BasePaths:    basePaths,
+		}
+		for operations := pathItems.Value().GetOperations().First(); operations != nil; operations = operations.Next() {
+			operation := operations.Value()
+
+			schemes := operation.Schemes
+			if len(schemes) == 0 {
+				schemes = model.Model.Schemes
+			}
+			security := operation.Security
+			if security == nil {
+				security = model.Model.Security
+			}
+			var responseCodes []string
+			if operation.Responses != nil {
+				responseCodes = responseCodesOf(operation.Responses.Codes, operation.Responses.Default != nil)
+			}
+
+			pathItem.Operations = append(pathItem.Operations, &inventory.Operation{
+				Method:        strings.ToUpper(operations.Key()),
+				PathTemplate:  pathItem.PathTemplate,
+				Params:        v2Params(pathItems.Value().Parameters, operation.Parameters),
+				Deprecated:    operation.Deprecated,
+				Sunset:        sunsetOf(operation.Extensions),
+				Security:      securitySchemeNames(security),
+				ResponseCodes: responseCodes,
+				Schemes:       schemes,
+				Source:        source,
+			})
+		}
+		inv.PathItems = append(inv.PathItems, pathItem)
+	}
+	return inv
+}
+
+// v3Params merges the path item parameters with the operation parameters, the
+// latter override the former when both have the same name and location.
+func v3Params(pathItemParams, operationParams []*v3.Parameter) []inventory.Param {
+	var params []inventory.Param
+	for _, parameter := range append(pathItemParams, operationParams...) {
+		param := inventory.Param{
+			Name:     parameter.Name,
+			In:       parameter.In,
+			Required: parameter.Required != nil && *parameter.Required,
+		}
+		if parameter.Schema != nil {
+			if schema := parameter.Schema.Schema(); schema != nil {
+				param.Schema = schemaOf(schema)
+			}
+		}
+		params = mergeParam(params, param)
+	}
+	return params
+}
+
+// v2Params merges the path item parameters with the operation parameters, the
+// latter override the former when both have the same name and location.
+func v2Params(pathItemParams, operationParams []*v2.Parameter) []inventory.Param {
+	var params []inventory.Param
+	for _, parameter := range append(pathItemParams, operationParams...) {
+		param := inventory.Param{
+			Name:     parameter.Name,
+			In:       parameter.In,
+			Required: parameter.Required != nil && *parameter.Required,
+			Schema: inventory.Schema{
+				Type:      parameter.Type,
+				Format:    parameter.Format,
+				Pattern:   parameter.Pattern,
+				Enum:      enumOf(parameter.Enum),
+				MinLength: int64Of(parameter.MinLength),
+				MaxLength: int64Of(parameter.MaxLength),
+			},
+		}
+		if parameter.Schema != nil {
+			if schema := parameter.Schema.Schema(); schema != nil {
+				param.Schema = schemaOf(schema)
+			}
+		}
+		params = mergeParam(params, param)
+	}
+	return params
+}
+
+func mergeParam(params []inventory.Param, param inventory.Param) []inventory.Param {
+	for i := range params {
+		if params[i].Name == param.Name && params[i].In == param.In {
+			params[i] = param
+			return params
+		}
+	}
+	return append(params, param)
+}
+
+func schemaOf(schema *base.Schema) inventory.Schema {
+	s := inventory.Schema{
+		Format:    schema.Format,
+		Pattern:   schema.Pattern,
+		Enum:      enumOf(schema.Enum),
+		MinLength: schema.MinLength,
+		MaxLength: schema.MaxLength,
+	}
+	if len(schema.Type) > 0 {
+		s.Type = schema.Type[0]
+	}
+	return s
+}
+
+// enumOf returns the scalar enum values, formatted as strings.
+func enumOf(enum []*yaml.Node) []string {
+	var values []string
+	for _, node := range enum {
+		if node != nil && node.Kind == yaml.ScalarNode {
+			values = append(values, node.Value)
+		}
+	}
+	return values
+}
+
+func int64Of(value *int) *int64 {
+	if value == nil {
+		return nil
+	}
+	v := int64(*value)
+	return &v
+}
+
+func responseCodesOf[T any](codes *orderedmap.Map[string, T], hasDefault bool) []string {
+	var responseCodes []string
+	for code := codes.First(); code != nil; code = code.Next() {
+		responseCodes = append(responseCodes, code.Key())
+	}
+	if hasDefault {
+		responseCodes = append(responseCodes, "default")
+	}
+	return responseCodes
+}
+
+// securitySchemeNames returns the names of the security schemes referenced by
+// the given security requirements.
+func securitySchemeNames(security []*base.SecurityRequirement) []string {
+	var names []string
+	for _, requirement := range security {
+		if requirement == nil || requirement.Requirements == nil {
+			continue
+		}
+		for scheme := requirement.Requirements.First(); scheme != nil; scheme = scheme.Next() {
+			names = append(names, scheme.Key())
+		}
+	}
+	return names
+}
+
+// sunsetOf parses the `x-sunset` extension, either as a date or a timestamp.
+func sunsetOf(extensions *orderedmap.Map[string, *yaml.Node]) *time.Time {
+	if extensions == nil {
+		return nil
+	}
+	node, ok := extensions.Get(sunsetExtension)
+	if !ok || node == nil {
+		return nil
+	}
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if sunset, err := time.Parse(layout, node.Value); err == nil {
+			return &sunset
+		}
+	}
+	return nil
+}
+
+// serverBasePaths derives the base paths from the server URLs, expanding the
+// server variables to every value of their enum, or to their default value.
+// Returns nil if every server serves the API at the root.
+func serverBasePaths(servers []*v3.Server) []string {
+	var basePaths []string
+	servedAtRoot := false
+	for _, server := range servers {
+		for _, serverURL := range expandServerVariables(server.URL, server.Variables) {
+			basePath := ""
+			if u, err := url.Parse(serverURL); err == nil {
+				basePath = strings.TrimSuffix(u.Path, "/")
+			}
+			if basePath == "" {
+				servedAtRoot = true
+			}
+			if !slices.Contains(basePaths, basePath) {
+				basePaths = append(basePaths, basePath)
+			}
+		}
+	}
+	if servedAtRoot && len(basePaths) == 1 {
+		return nil
+	}
+	return basePaths
+}
+
+func expandServerVariables(serverURL string, variables *orderedmap.Map[string, *v3.ServerVariable]) []string {
+	serverURLs := []string{serverURL}
+	for variable := variables.First(); variable != nil; variable = variable.Next() {
+		values := variable.Value().Enum
+		if len(values) == 0 {
+			values = []string{variable.Value().Default}
+		}
+
+		placeholder := "{" + variable.Key() + "}"
+		var expanded []string
+		for _, u := range serverURLs {
+			if !strings.Contains(u, placeholder) {
+				expanded = append(expanded, u)
+				continue
+			}
+			for _, value := range values {
+				expanded = append(expanded, strings.ReplaceAll(u, placeholder, value))
+			}
+		}
+		serverURLs = expanded
+	}
+	return serverURLs
+}
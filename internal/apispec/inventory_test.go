@@ -0,0 +1,240 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package apispec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+func TestBuildInventory(t *testing.T) {
+	sunset := time.Date(2025, time.January, 31, 0, 0, 0, 0, time.UTC)
+	minLength, maxLength := int64(1), int64(36)
+
+	tests := []struct {
+		name      string
+		specBytes string
+		want      *inventory.Inventory
+	}{
+		{
+			name: "openapi 3",
+			specBytes: `openapi: 3.0.3
+info:
+  title: test
+  version: 1.0.0
+servers:
+  - url: https://api.example.com/v2/
+security:
+  - apiKey: []
+paths:
+  /users/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: integer
+          format: int64
+    get:
+      deprecated: true
+      x-sunset: "2025-01-31"
+      parameters:
+        - name: fields
+          in: query
+          schema:
+            type: string
+            pattern: ^[a-z,]+$
+            minLength: 1
+            enum: [name, email]
+      responses:
+        "200":
+          description: ok
+        default:
+          description: error
+    delete:
+      security:
+        - oauth: [admin]
+      responses:
+        "204":
+          description: deleted
+`,
+			want: &inventory.Inventory{
+				Source:  "spec.yaml",
+				Version: "3.0.3",
+				PathItems: []*inventory.PathItem{
+					{
+						PathTemplate: "/users/{id}",
+						BasePaths:    []string{"/v2"},
+						Operations: []*inventory.Operation{
+							{
+								Method:       "GET",
+								PathTemplate: "/users/{id}",
+								Params: []inventory.Param{
+									{Name: "id", In: "path", Required: true, Schema: inventory.Schema{Type: "integer", Format: "int64"}},
+									{Name: "fields", In: "query", Schema: inventory.Schema{Type: "string", Pattern: "^[a-z,]+$", Enum: []string{"name", "email"}, MinLength: &minLength}},
+								},
+								Deprecated:    true,
+								Sunset:        &sunset,
+								Security:      []string{"apiKey"},
+								ResponseCodes: []string{"200", "default"},
+								Source:        "spec.yaml",
+							},
+							{
+								Method:       "DELETE",
+								PathTemplate: "/users/{id}",
+								Params: []inventory.Param{
+									{Name: "id", In: "path", Required: true, Schema: inventory.Schema{Type: "integer", Format: "int64"}},
+								},
+								Security:      []string{"oauth"},
+								ResponseCodes: []string{"204"},
+								Source:        "spec.yaml",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "swagger 2.0 with basePath and schemes",
+			specBytes: `swagger: "2.0"
+info:
+  title: test
+  version: 1.0.0
+basePath: /v1/
+schemes:
+  - https
+paths:
+  /users/{id}:
+    get:
+      deprecated: true
+      parameters:
+        - name: id
+          in: path
+          required: true
+          type: string
+          format: uuid
+          maxLength: 36
+      responses:
+        "200":
+          description: ok
+    put:
+      schemes:
+        - http
+      responses:
+        "200":
+          description: ok
+`,
+			want: &inventory.Inventory{
+				Source:  "spec.yaml",
+				Version: "2.0",
+				PathItems: []*inventory.PathItem{
+					{
+						PathTemplate: "/users/{id}",
+						BasePaths:    []string{"/v1"},
+						Operations: []*inventory.Operation{
+							{
+								Method:       "GET",
+								PathTemplate: "/users/{id}",
+								Params: []inventory.Param{
+									{Name: "id", In: "path", Required: true, Schema: inventory.Schema{Type: "string", Format: "uuid", MaxLength: &maxLength}},
+								},
+								Deprecated:    true,
+								ResponseCodes: []string{"200"},
+								Schemes:       []string{"https"},
+								Source:        "spec.yaml",
+							},
+							{
+								Method:        "PUT",
+								PathTemplate:  "/users/{id}",
+								ResponseCodes: []string{"200"},
+								Schemes:       []string{"http"},
+								Source:        "spec.yaml",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildInventory([]byte(tt.specBytes), "spec.yaml", nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServerBasePaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers string
+		want    []string
+	}{
+		{
+			name:    "no servers",
+			servers: "",
+			want:    nil,
+		},
+		{
+			name: "served at the root",
+			servers: `servers:
+  - url: https://api.example.com
+  - url: /
+`,
+			want: nil,
+		},
+		{
+			name: "absolute and relative urls",
+			servers: `servers:
+  - url: https://api.example.com/v2
+  - url: /v3/
+  - url: https://staging.example.com
+`,
+			want: []string{"/v2", "/v3", ""},
+		},
+		{
+			name: "server variables",
+			servers: `servers:
+  - url: https://{env}.example.com/{basePath}/{version}
+    variables:
+      env:
+        default: api
+        enum: [api, staging]
+      basePath:
+        default: public
+      version:
+        default: v1
+        enum: [v1, v2]
+`,
+			want: []string{"/public/v1", "/public/v2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specBytes := `openapi: 3.0.3
+info:
+  title: test
+  version: 1.0.0
+` + tt.servers + `paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: ok
+`
+			inv, err := BuildInventory([]byte(specBytes), "", nil)
+			require.NoError(t, err)
+			require.Len(t, inv.PathItems, 1)
+			assert.Equal(t, tt.want, inv.PathItems[0].BasePaths)
+		})
+	}
+}
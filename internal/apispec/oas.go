@@ -4,8 +4,20 @@
 package apispec
 
 import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
 	"github.com/pb33f/libopenapi"
+	"github.com/pb33f/libopenapi/datamodel"
 	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
+	"github.com/pb33f/libopenapi/utils"
+	"gopkg.in/yaml.v3"
+
+	"github.com/5gsec/api-speculator/internal/inventory"
 )
 
 func BuildOASV3Model(specBytes []byte) (*libopenapi.DocumentModel[v3.Document], error) {
@@ -14,9 +26,90 @@ func BuildOASV3Model(specBytes []byte) (*libopenapi.DocumentModel[v3.Document],
 		return nil, err
 	}
 
-	docModel, errors := document.BuildV3Model()
-	if len(errors) > 0 {
-		return nil, errors[0]
+	docModel, errs := document.BuildV3Model()
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 	return docModel, nil
 }
+
+// BuildInventory detects the version of the given specification, builds the
+// corresponding OpenAPI 3 or Swagger 2.0 model and converts it to an inventory.
+// The source is the file path or URL the specification was loaded from, relative
+// references are resolved from its location. The optional remoteURLHandler is
+// used to fetch remote references of specs loaded from a URL.
+func BuildInventory(specBytes []byte, source string, remoteURLHandler utils.RemoteURLHandler) (*inventory.Inventory, error) {
+	docConfig, err := documentConfiguration(source)
+	if err != nil {
+		return nil, err
+	}
+	if docConfig.BaseURL != nil {
+		docConfig.RemoteURLHandler = remoteURLHandler
+	}
+
+	document, err := libopenapi.NewDocumentWithConfiguration(specBytes, docConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if document.GetSpecInfo().SpecFormat == datamodel.OAS2 {
+		docModel, errs := document.BuildV2Model()
+		if len(errs) > 0 {
+			return nil, errors.Join(errs...)
+		}
+		return inventoryFromV2Model(docModel, source), nil
+	}
+
+	docModel, errs := document.BuildV3Model()
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return inventoryFromV3Model(docModel, source), nil
+}
+
+// documentConfiguration configures libopenapi to resolve relative references
+// from the location of the given source, either a file path or a URL. Remote
+// references are always resolved.
+func documentConfiguration(source string) (*datamodel.DocumentConfiguration, error) {
+	docConfig := &datamodel.DocumentConfiguration{
+		AllowRemoteReferences: true,
+	}
+	if source == "" {
+		return docConfig, nil
+	}
+
+	if IsURL(source) {
+		baseURL, err := url.Parse(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse spec URL `%s`: %w", source, err)
+		}
+		baseURL.Path = path.Dir(baseURL.Path)
+		baseURL.RawQuery = ""
+		baseURL.Fragment = ""
+		docConfig.BaseURL = baseURL
+		return docConfig, nil
+	}
+
+	docConfig.AllowFileReferences = true
+	docConfig.BasePath = filepath.Dir(source)
+	docConfig.SpecFilePath = filepath.Base(source)
+	return docConfig, nil
+}
+
+// IsURL reports whether the spec location is an HTTP(S) URL rather than a file
+// path.
+func IsURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// IsRootDocument reports whether the given bytes hold an OpenAPI or Swagger
+// root document, as opposed to a fragment referenced by one.
+func IsRootDocument(specBytes []byte) (bool, error) {
+	var document map[string]any
+	if err := yaml.Unmarshal(specBytes, &document); err != nil {
+		return false, err
+	}
+	_, isOpenAPI := document["openapi"]
+	_, isSwagger := document["swagger"]
+	return isOpenAPI || isSwagger, nil
+}
@@ -6,6 +6,11 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -14,6 +19,10 @@ import (
 
 const defaultConfigFilePath = "config/default.yaml"
 const defaultJSONReportFilePath = "findings.json"
+const defaultProxyListen = ":8080"
+const defaultBatchSize = 1000
+const defaultMaxAggregationKeys = 100000
+const defaultTimestampField = "timestamp"
 
 type Database struct {
 	Uri        string `json:"uri"`
@@ -21,6 +30,10 @@ type Database struct {
 	Password   string `json:"password"`
 	Name       string `json:"name"`
 	Collection string `json:"collection"`
+
+	// TimestampField is the field of the API event documents holding the time
+	// they were observed at, as a BSON date. Defaults to `timestamp`.
+	TimestampField string `json:"timestampField,omitempty"`
 }
 
 type Environment struct {
@@ -32,39 +45,377 @@ type Exporter struct {
 	JsonReportFilePath string `json:"jsonReportFilePath,omitempty"`
 }
 
+// Traffic sources.
+const (
+	TrafficSourceMongoDB = "mongodb"
+	TrafficSourceJSONL   = "jsonl"
+	TrafficSourceHAR     = "har"
+	TrafficSourceEnvoy   = "envoy"
+	TrafficSourceNginx   = "nginx"
+	TrafficSourceOTel    = "otel"
+	TrafficSourcePcap    = "pcap"
+	// TrafficSourceProxy is used by `speculator proxy`, the traffic is the one
+	// it proxies.
+	TrafficSourceProxy = "proxy"
+)
+
+type Traffic struct {
+	// Source of the API events, defaults to TrafficSourceMongoDB.
+	Source string `json:"source,omitempty"`
+
+	// Path of the file the events are read from, "-" for the standard input.
+	// Unused by the MongoDB source.
+	Path string `json:"path,omitempty"`
+
+	// Envoy configures the parsing of the `envoy` source access logs.
+	Envoy EnvoyAccessLog `json:"envoy,omitempty"`
+
+	// Nginx configures the parsing of the `nginx` source access logs.
+	Nginx NginxAccessLog `json:"nginx,omitempty"`
+
+	// OTel configures the OTLP/HTTP receiver of the `otel` source.
+	OTel OTelReceiver `json:"otel,omitempty"`
+
+	// Since and Until restrict the scan to the traffic observed in the window,
+	// either as RFC 3339 times, dates or durations relative to now such as
+	// `7d` or `12h`. Only supported by the MongoDB source.
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+
+	// Window is resolved from Since and Until.
+	Window TimeWindow `json:"-" mapstructure:"-"`
+}
+
+// TimeWindow bounds the time the scanned traffic was observed at, the zero
+// time leaves a side unbounded.
+type TimeWindow struct {
+	Since time.Time
+	Until time.Time
+}
+
+// IsSet reports whether the window bounds the traffic.
+func (w TimeWindow) IsSet() bool {
+	return !w.Since.IsZero() || !w.Until.IsZero()
+}
+
+// ParseWindowTime parses an RFC 3339 time, a date or a duration before now such
+// as `90m`, `12h`, `7d` or `2w`.
+func ParseWindowTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	if unit, exists := units[value[max(len(value)-1, 0):]]; exists {
+		if count, err := strconv.Atoi(value[:len(value)-1]); err == nil && count >= 0 {
+			return now.Add(-time.Duration(count) * unit), nil
+		}
+	} else if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return now.Add(-duration), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time `%s`, expected an RFC 3339 time, a date or a duration such as `7d`", value)
+}
+
+// resolveWindow parses Since and Until into Window.
+func (t *Traffic) resolveWindow(now time.Time) error {
+	var err error
+	if t.Since != "" {
+		if t.Window.Since, err = ParseWindowTime(t.Since, now); err != nil {
+			return fmt.Errorf("configuration contains an invalid traffic since: %w", err)
+		}
+	}
+	if t.Until != "" {
+		if t.Window.Until, err = ParseWindowTime(t.Until, now); err != nil {
+			return fmt.Errorf("configuration contains an invalid traffic until: %w", err)
+		}
+	}
+	if !t.Window.Since.IsZero() && !t.Window.Until.IsZero() && !t.Window.Since.Before(t.Window.Until) {
+		return fmt.Errorf("configuration contains a traffic since that is not before until")
+	}
+	return nil
+}
+
+// OTelReceiver configures a local OTLP/HTTP endpoint receiving traces.
+type OTelReceiver struct {
+	// Listen is the receiver address, e.g. `:4318`. Disabled if empty.
+	Listen string `json:"listen,omitempty"`
+
+	// Duration traces are received for before the scan, until interrupted if 0.
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// NginxAccessLog describes the format of NGINX or Apache access logs.
+type NginxAccessLog struct {
+	// Format is either `common`, `combined` (default) or an NGINX `log_format`
+	// string.
+	Format string `json:"format,omitempty"`
+
+	// ServiceName is used for the events when the format doesn't log the host.
+	ServiceName string `json:"serviceName,omitempty"`
+}
+
+// EnvoyAccessLog describes the format of Envoy or Istio access logs. Text lines
+// are parsed using Format, JSON lines using JSONKeys.
+type EnvoyAccessLog struct {
+	// Format is the text format string, defaults to Envoy's default format.
+	Format string `json:"format,omitempty"`
+
+	// JSONKeys defaults to the keys of Istio's JSON access logs, key by key.
+	JSONKeys EnvoyJSONKeys `json:"jsonKeys,omitempty"`
+}
+
+// EnvoyJSONKeys names the keys of JSON access logs holding the request fields.
+type EnvoyJSONKeys struct {
+	Authority    string `json:"authority,omitempty"`
+	Method       string `json:"method,omitempty"`
+	Path         string `json:"path,omitempty"`
+	ResponseCode string `json:"responseCode,omitempty"`
+}
+
+// Proxy configures the reverse proxy of `speculator proxy`.
+type Proxy struct {
+	// Listen is the proxy address, defaults to `:8080`.
+	Listen string `json:"listen,omitempty"`
+
+	// Upstream is the URL of the proxied service.
+	Upstream string `json:"upstream,omitempty"`
+
+	// AdminListen is the address serving the live findings on `/findings`.
+	// Disabled if empty.
+	AdminListen string `json:"adminListen,omitempty"`
+}
+
+// DefaultIgnorePresets are the ignore rule presets applied unless configured
+// otherwise.
+var DefaultIgnorePresets = []string{"static-assets", "health-checks", "root"}
+
+// Ignore configures the traffic excluded from the scan. Ignored events are
+// dropped before any detection, and the spec operations that path and method
+// rules match are never reported as orphan APIs.
+type Ignore struct {
+	// Presets names the built-in rules applied, defaults to
+	// DefaultIgnorePresets.
+	Presets []string `json:"presets"`
+
+	Rules []IgnoreRule `json:"rules,omitempty"`
+}
+
+// IgnoreRule matches the events that all its conditions match, a condition
+// matches if any of its values matches. Empty conditions match every event.
+type IgnoreRule struct {
+	// Name identifies the rule in the report.
+	Name string `json:"name"`
+
+	// Paths are globs matching the request path, `*` matches within a path
+	// segment and `**` across segments.
+	Paths []string `json:"paths,omitempty"`
+
+	// PathRegex are regular expressions matching the request path.
+	PathRegex []string `json:"pathRegex,omitempty"`
+
+	Methods []string `json:"methods,omitempty"`
+
+	// Services are glob patterns matching the service name.
+	Services []string `json:"services,omitempty"`
+
+	// StatusCodes are response codes or ranges such as `4XX`.
+	StatusCodes []string `json:"statusCodes,omitempty"`
+
+	// ContentTypes are response media types, e.g. `text/html` or `image/*`.
+	ContentTypes []string `json:"contentTypes,omitempty"`
+}
+
+// Detection tunes the findings of a scan.
+type Detection struct {
+	// ExcludeNotFoundShadowApis excludes the shadow APIs only ever answered with
+	// 404 or 405 from the findings, as those requests never reached a handler.
+	ExcludeNotFoundShadowApis bool `json:"excludeNotFoundShadowApis,omitempty"`
+}
+
+// Processing bounds the resources used by a scan.
+type Processing struct {
+	// BatchSize is the number of events fetched from the database per round
+	// trip, defaults to 1000.
+	BatchSize int32 `json:"batchSize,omitempty"`
+
+	// MaxAggregationKeys is the maximum number of distinct APIs and unmapped
+	// services held in memory, defaults to 100000. The occurrences of the ones
+	// beyond are counted as dropped.
+	MaxAggregationKeys int `json:"maxAggregationKeys,omitempty"`
+}
+
 type APICollections struct {
 	CollectionTemplate string   `json:"collectionTemplate"` // e.g., "obs_system_api_collections_<tenant_id>"
 	NameList           []string `json:"nameList"`           // actual collection names to filter
 }
 
+// Spec maps an API specification to the services it documents. Events are
+// routed to the first spec whose matchers all match; a spec without matchers
+// matches every event.
+type Spec struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path"` // Either filepath or URL
+
+	// ServiceNames holds glob patterns matched against the event's `:authority`.
+	ServiceNames []string `json:"serviceNames,omitempty"`
+
+	// ClusterNames holds glob patterns matched against the event's cluster name.
+	ClusterNames []string `json:"clusterNames,omitempty"`
+
+	PathPrefixes PathPrefixes `json:"pathPrefixes,omitempty"`
+
+	// Download is used when Path is a URL.
+	Download SpecDownload `json:"download,omitempty"`
+}
+
+// SpecDownload configures how a spec is downloaded.
+type SpecDownload struct {
+	Headers     map[string]string `json:"headers,omitempty"`
+	BearerToken Secret            `json:"bearerToken,omitempty"`
+	BasicAuth   BasicAuth         `json:"basicAuth,omitempty"`
+
+	// CAFile is a PEM bundle of the CAs trusted in addition to the system ones.
+	CAFile string `json:"caFile,omitempty"`
+	// CertFile and KeyFile hold the PEM client certificate and key.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// CacheDir enables caching the downloaded spec. The cached copy is
+	// revalidated using its ETag/Last-Modified and used as a fallback when the
+	// spec cannot be downloaded.
+	CacheDir string `json:"cacheDir,omitempty"`
+}
+
+type BasicAuth struct {
+	Username string `json:"username,omitempty"`
+	Password Secret `json:"password,omitempty"`
+}
+
+// Secret is read either from an environment variable or from a file, so that
+// it is never stored in the configuration itself.
+type Secret struct {
+	Env  string `json:"env,omitempty"`
+	File string `json:"file,omitempty"`
+}
+
+// IsSet reports whether the secret is configured.
+func (s Secret) IsSet() bool {
+	return s.Env != "" || s.File != ""
+}
+
+// Value reads the secret, trimming surrounding whitespace from files.
+func (s Secret) Value() (string, error) {
+	if s.Env != "" {
+		value, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable `%s` is not set", s.Env)
+		}
+		return value, nil
+	}
+	if s.File != "" {
+		value, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(value)), nil
+	}
+	return "", nil
+}
+
+// PathPrefixes rewrites the path prefixes derived from the spec `servers` or
+// `basePath`, e.g. when a gateway rewrites paths before traffic is observed.
+type PathPrefixes struct {
+	// Add holds additional prefixes the spec paths are served under.
+	Add []string `json:"add,omitempty"`
+
+	// Strip holds prefixes removed from the derived prefixes.
+	Strip []string `json:"strip,omitempty"`
+}
+
 type Configuration struct {
-	Database       Database       `json:"database"`
-	Environment    Environment    `json:"environment"`
-	OpenAPISpec    string         `json:"openAPISpec"`
+	Database    Database    `json:"database,omitempty"`
+	Environment Environment `json:"environment"`
+	Traffic     Traffic     `json:"traffic,omitempty"`
+	Proxy       Proxy       `json:"proxy,omitempty"`
+	Processing  Processing  `json:"processing,omitempty"`
+	Ignore      Ignore      `json:"ignore,omitempty"`
+	Detection   Detection   `json:"detection,omitempty"`
+	// SuppressionsFile is the path of the file listing the suppressions,
+	// loaded into Suppressions.
+	SuppressionsFile string        `json:"suppressionsFile,omitempty"`
+	Suppressions     []Suppression `json:"-" mapstructure:"-"`
+	// OpenAPISpec is kept for backward compatibility, it is equivalent to a
+	// single entry in Specs without matchers.
+	OpenAPISpec    string         `json:"openAPISpec,omitempty"`
+	Specs          []Spec         `json:"specs,omitempty"`
 	Exporter       Exporter       `json:"exporter,omitempty"`
 	ScanName       string         `json:"scanName"`
 	APICollections APICollections `json:"apiCollections,omitempty"`
 }
 
 func (c *Configuration) validate() error {
-	if c.Database.Uri == "" {
-		return fmt.Errorf("configuration does not contain a valid database URI")
-	}
-	if c.Database.User == "" {
-		return fmt.Errorf("configuration does not contain a valid database user")
+	switch c.Traffic.Source {
+	case TrafficSourceMongoDB:
+		if err := c.Database.validate(); err != nil {
+			return err
+		}
+	case TrafficSourceJSONL, TrafficSourceHAR, TrafficSourceEnvoy, TrafficSourceNginx, TrafficSourcePcap:
+		if c.Traffic.Path == "" {
+			return fmt.Errorf("configuration does not contain a valid traffic file path")
+		}
+	case TrafficSourceOTel:
+		if c.Traffic.Path == "" && c.Traffic.OTel.Listen == "" {
+			return fmt.Errorf("configuration does not contain a valid traffic file path or OTLP receiver address")
+		}
+	case TrafficSourceProxy:
+		upstream, err := url.Parse(c.Proxy.Upstream)
+		if err != nil || (upstream.Scheme != "http" && upstream.Scheme != "https") || upstream.Host == "" {
+			return fmt.Errorf("configuration does not contain a valid proxy upstream URL")
+		}
+	default:
+		return fmt.Errorf("configuration contains an unknown traffic source `%s`", c.Traffic.Source)
 	}
-	if c.Database.Password == "" {
-		return fmt.Errorf("configuration does not contain a valid database password")
+	if c.Traffic.Window.IsSet() && c.Traffic.Source != TrafficSourceMongoDB {
+		return fmt.Errorf("configuration contains a traffic time window, which is only supported by the `%s` traffic source", TrafficSourceMongoDB)
 	}
-	if c.Database.Name == "" {
-		return fmt.Errorf("configuration does not contain a valid database name")
+
+	if len(c.Specs) == 0 {
+		return fmt.Errorf("configuration does not contain a valid OpenAPI Specification filepath or URL")
 	}
-	if c.Database.Collection == "" {
-		return fmt.Errorf("configuration does not contain a valid database collection name")
+	for idx, spec := range c.Specs {
+		if spec.Path == "" {
+			return fmt.Errorf("configuration does not contain a valid OpenAPI Specification filepath or URL for spec at index %d", idx)
+		}
+		if (spec.Download.CertFile == "") != (spec.Download.KeyFile == "") {
+			return fmt.Errorf("configuration must contain both a client certificate and key file for spec `%s`", spec.Name)
+		}
+		if spec.Download.BearerToken.IsSet() && spec.Download.BasicAuth.Username != "" {
+			return fmt.Errorf("configuration must contain either a bearer token or basic auth for spec `%s`", spec.Name)
+		}
+		for _, pattern := range append(spec.ServiceNames, spec.ClusterNames...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("configuration contains an invalid pattern `%s` for spec `%s`: %w", pattern, spec.Name, err)
+			}
+		}
 	}
 
-	if c.OpenAPISpec == "" {
-		return fmt.Errorf("configuration does not contain a valid OpenAPI Specification filepath or URL")
+	for idx, rule := range c.Ignore.Rules {
+		if rule.Name == "" {
+			return fmt.Errorf("configuration does not contain a name for the ignore rule at index %d", idx)
+		}
+		if len(rule.Paths)+len(rule.PathRegex)+len(rule.Methods)+len(rule.Services)+len(rule.StatusCodes)+len(rule.ContentTypes) == 0 {
+			return fmt.Errorf("configuration does not contain any condition for the ignore rule `%s`", rule.Name)
+		}
+		for _, pattern := range rule.Services {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("configuration contains an invalid pattern `%s` for ignore rule `%s`: %w", pattern, rule.Name, err)
+			}
+		}
 	}
 
 	if c.Exporter.JsonReportFilePath == "" {
@@ -74,6 +425,25 @@ func (c *Configuration) validate() error {
 	return nil
 }
 
+func (d *Database) validate() error {
+	if d.Uri == "" {
+		return fmt.Errorf("configuration does not contain a valid database URI")
+	}
+	if d.User == "" {
+		return fmt.Errorf("configuration does not contain a valid database user")
+	}
+	if d.Password == "" {
+		return fmt.Errorf("configuration does not contain a valid database password")
+	}
+	if d.Name == "" {
+		return fmt.Errorf("configuration does not contain a valid database name")
+	}
+	if d.Collection == "" {
+		return fmt.Errorf("configuration does not contain a valid database collection name")
+	}
+	return nil
+}
+
 func New(configFilePath string, logger *zap.SugaredLogger) (Configuration, error) {
 	if configFilePath == "" {
 		configFilePath = defaultConfigFilePath
@@ -81,6 +451,7 @@ func New(configFilePath string, logger *zap.SugaredLogger) (Configuration, error
 	}
 
 	viper.SetConfigFile(configFilePath)
+	viper.SetDefault("ignore.presets", DefaultIgnorePresets)
 	if err := viper.ReadInConfig(); err != nil {
 		return Configuration{}, fmt.Errorf("failed to read config file: %w", err)
 	}
@@ -94,6 +465,34 @@ func New(configFilePath string, logger *zap.SugaredLogger) (Configuration, error
 		logger.Warn("using default JSON report file path: ", defaultJSONReportFilePath)
 	}
 
+	if config.Traffic.Source == "" {
+		config.Traffic.Source = TrafficSourceMongoDB
+	}
+	if config.Database.TimestampField == "" {
+		config.Database.TimestampField = defaultTimestampField
+	}
+	if err := config.Traffic.resolveWindow(time.Now()); err != nil {
+		return Configuration{}, err
+	}
+	if config.Processing.BatchSize <= 0 {
+		config.Processing.BatchSize = defaultBatchSize
+	}
+	if config.Processing.MaxAggregationKeys <= 0 {
+		config.Processing.MaxAggregationKeys = defaultMaxAggregationKeys
+	}
+	if config.Traffic.Source == TrafficSourceProxy && config.Proxy.Listen == "" {
+		config.Proxy.Listen = defaultProxyListen
+	}
+
+	if config.OpenAPISpec != "" {
+		config.Specs = append(config.Specs, Spec{Path: config.OpenAPISpec})
+	}
+	for idx := range config.Specs {
+		if config.Specs[idx].Name == "" {
+			config.Specs[idx].Name = config.Specs[idx].Path
+		}
+	}
+
 	if config.ScanName == "" {
 		config.ScanName = fmt.Sprintf("openapi-scan-%s", time.Now().Format("20060102-150405"))
 		logger.Infof("scanName not provided, using generated name: %s", config.ScanName)
@@ -102,6 +501,13 @@ func New(configFilePath string, logger *zap.SugaredLogger) (Configuration, error
 	if err := config.validate(); err != nil {
 		return Configuration{}, err
 	}
+	if config.SuppressionsFile != "" {
+		suppressions, err := LoadSuppressions(config.SuppressionsFile)
+		if err != nil {
+			return Configuration{}, err
+		}
+		config.Suppressions = suppressions
+	}
 
 	dbUser := config.Database.User
 	dbPassword := config.Database.Password
@@ -109,6 +515,19 @@ func New(configFilePath string, logger *zap.SugaredLogger) (Configuration, error
 	config.Database.User = ""
 	config.Database.Password = ""
 
+	// The download headers may hold credentials, e.g. an API key.
+	specs := config.Specs
+	config.Specs = make([]Spec, len(specs))
+	for idx, spec := range specs {
+		config.Specs[idx] = spec
+		if len(spec.Download.Headers) > 0 {
+			config.Specs[idx].Download.Headers = make(map[string]string, len(spec.Download.Headers))
+			for key := range spec.Download.Headers {
+				config.Specs[idx].Download.Headers[key] = ""
+			}
+		}
+	}
+
 	if logger.Level().String() == "debug" {
 		bytes, err := json.Marshal(config)
 		if err != nil {
@@ -119,6 +538,14 @@ func New(configFilePath string, logger *zap.SugaredLogger) (Configuration, error
 
 	config.Database.User = dbUser
 	config.Database.Password = dbPassword
+	config.Specs = specs
 
 	return config, nil
 }
+
+// NewProxy reads the configuration of `speculator proxy`, the proxied traffic
+// replaces the configured traffic source.
+func NewProxy(configFilePath string, logger *zap.SugaredLogger) (Configuration, error) {
+	viper.Set("traffic.source", TrafficSourceProxy)
+	return New(configFilePath, logger)
+}
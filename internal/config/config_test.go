@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseWindowTime(t *testing.T) {
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+	for value, want := range map[string]time.Time{
+		"2024-05-01T10:00:00Z": time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+		"2024-05-01":           time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"7d":                   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		"2w":                   time.Date(2024, 4, 24, 12, 0, 0, 0, time.UTC),
+		"90m":                  time.Date(2024, 5, 8, 10, 30, 0, 0, time.UTC),
+	} {
+		got, err := ParseWindowTime(value, now)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	for _, value := range []string{"", "yesterday", "-7d", "7x", "d"} {
+		_, err := ParseWindowTime(value, now)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestTrafficResolveWindow(t *testing.T) {
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+
+	traffic := Traffic{Since: "7d"}
+	require.NoError(t, traffic.resolveWindow(now))
+	assert.Equal(t, TimeWindow{Since: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}, traffic.Window)
+
+	traffic = Traffic{Since: "1d", Until: "2d"}
+	assert.Error(t, traffic.resolveWindow(now))
+}
+
+func TestLoadSuppressions(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "suppressions.yaml")
+	require.NoError(t, os.WriteFile(filePath, []byte(`
+suppressions:
+  - findingType: shadow
+    method: GET
+    path: /internal/{id}
+    service: "users*"
+    owner: team-users
+    reason: accepted risk
+    expires: 2024-06-30
+  - findingType: orphan
+    path: /legacy
+    owner: team-legacy
+    reason: removed next release
+`), 0o600))
+
+	suppressions, err := LoadSuppressions(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, []Suppression{
+		{FindingType: FindingTypeShadow, Method: "GET", Path: "/internal/{id}", Service: "users*", Owner: "team-users", Reason: "accepted risk", Expires: time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)},
+		{FindingType: FindingTypeOrphan, Path: "/legacy", Owner: "team-legacy", Reason: "removed next release"},
+	}, suppressions)
+
+	assert.False(t, suppressions[0].Expired(time.Date(2024, 6, 30, 23, 0, 0, 0, time.UTC)))
+	assert.True(t, suppressions[0].Expired(time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)))
+	assert.False(t, suppressions[1].Expired(time.Now()))
+}
+
+func TestLoadSuppressions_Invalid(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown finding type": "suppressions:\n  - {findingType: ghost, path: /a, owner: o, reason: r}\n",
+		"missing path":         "suppressions:\n  - {findingType: shadow, owner: o, reason: r}\n",
+		"missing owner":        "suppressions:\n  - {findingType: shadow, path: /a, reason: r}\n",
+		"missing reason":       "suppressions:\n  - {findingType: shadow, path: /a, owner: o}\n",
+	} {
+		filePath := filepath.Join(t.TempDir(), "suppressions.yaml")
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0o600))
+		_, err := LoadSuppressions(filePath)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestNew_RedactsDownloadHeadersInDebugLog(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte(`traffic:
+  source: jsonl
+  path: events.jsonl
+specs:
+  - name: users
+    path: https://portal.example.com/users.yaml
+    download:
+      headers:
+        X-Api-Key: s3cr3t
+`), 0o644))
+
+	core, logs := observer.New(zapcore.DebugLevel)
+	cfg, err := New(configFile, zap.New(core).Sugar())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"x-api-key": "s3cr3t"}, cfg.Specs[0].Download.Headers)
+
+	entries := logs.FilterMessageSnippet("configuration:").All()
+	require.Len(t, entries, 1)
+	assert.NotContains(t, entries[0].Message, "s3cr3t")
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package config
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// Finding types a suppression applies to.
+const (
+	FindingTypeShadow = "shadow"
+	FindingTypeZombie = "zombie"
+	FindingTypeOrphan = "orphan"
+)
+
+// Suppression keeps a triaged finding, e.g. an accepted risk, out of the
+// findings of the report until it expires.
+type Suppression struct {
+	// FindingType is either `shadow`, `zombie` or `orphan`.
+	FindingType string `json:"findingType"`
+
+	// Method matches the request method, any method if empty.
+	Method string `json:"method,omitempty"`
+
+	// Path is a path template, e.g. `/users/{id}`, a parameter matches any
+	// single path segment.
+	Path string `json:"path"`
+
+	// Service is a glob pattern matching the service name, any service if
+	// empty. Orphan APIs have no service.
+	Service string `json:"service,omitempty"`
+
+	Owner  string `json:"owner"`
+	Reason string `json:"reason"`
+
+	// Expires is the last day the suppression applies, never expires if zero.
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// Expired reports whether the suppression no longer applies at now.
+func (s Suppression) Expired(now time.Time) bool {
+	return !s.Expires.IsZero() && !now.Before(s.Expires.AddDate(0, 0, 1))
+}
+
+// LoadSuppressions reads the `suppressions` list of a YAML or JSON file.
+func LoadSuppressions(filePath string) ([]Suppression, error) {
+	v := viper.New()
+	v.SetConfigFile(filePath)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read suppressions file: %w", err)
+	}
+
+	var file struct {
+		Suppressions []Suppression `json:"suppressions"`
+	}
+	if err := v.Unmarshal(&file, viper.DecodeHook(mapstructure.StringToTimeHookFunc(time.DateOnly))); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal suppressions file: %w", err)
+	}
+
+	for idx, suppression := range file.Suppressions {
+		if err := suppression.validate(); err != nil {
+			return nil, fmt.Errorf("suppressions file contains an invalid suppression at index %d: %w", idx, err)
+		}
+	}
+	return file.Suppressions, nil
+}
+
+func (s Suppression) validate() error {
+	switch s.FindingType {
+	case FindingTypeShadow, FindingTypeZombie, FindingTypeOrphan:
+	default:
+		return fmt.Errorf("unknown finding type `%s`", s.FindingType)
+	}
+	if s.Path == "" {
+		return fmt.Errorf("missing path template")
+	}
+	if s.Owner == "" {
+		return fmt.Errorf("missing owner")
+	}
+	if s.Reason == "" {
+		return fmt.Errorf("missing reason")
+	}
+	if _, err := path.Match(s.Service, ""); err != nil {
+		return fmt.Errorf("invalid service pattern `%s`: %w", s.Service, err)
+	}
+	return nil
+}
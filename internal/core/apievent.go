@@ -3,30 +3,49 @@ package core
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/5gsec/api-speculator/internal/apievent"
-	"github.com/emirpasic/gods/sets/hashset"
+	"github.com/5gsec/api-speculator/internal/config"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// findApiOperationDocuments fetches API documents based on collectionName, optional clusterId,
-// and optional collectionCriteria. Returns a set of unique apievent.
-func (m *Manager) findApiOperationDocuments(eventCollectionName, apiCollectionName string, clusterId int, nameList []string) (*hashset.Set, error) {
+// mongoEventSource streams the API events stored in MongoDB, grouped by API and
+// response status.
+type mongoEventSource struct {
+	mgr *Manager
+}
+
+func (s *mongoEventSource) Stream(ctx context.Context, emit func(apievent.ApiEvent) error) error {
+	cfg := s.mgr.Cfg
+	return s.mgr.findApiOperationDocuments(ctx, cfg.Database.Collection, cfg.APICollections.CollectionTemplate,
+		cfg.Environment.ClusterId, cfg.APICollections.NameList, emit)
+}
+
+// findApiOperationDocuments aggregates the API documents based on collectionName, optional clusterId,
+// and optional collectionCriteria, and calls emit for each group of them.
+func (m *Manager) findApiOperationDocuments(ctx context.Context, eventCollectionName, apiCollectionName string, clusterId int, nameList []string, emit func(apievent.ApiEvent) error) error {
 	// base filter: only Api operation documents
 	filter := bson.D{{Key: "operation", Value: "Api"}}
 
 	if clusterId != 0 {
 		filter = append(filter, bson.E{Key: "cluster_id", Value: clusterId})
 	}
+	if window := m.Cfg.Traffic.Window; window.IsSet() {
+		filter = append(filter, bson.E{Key: m.Cfg.Database.TimestampField, Value: timeWindowFilter(window)})
+	}
 	// if apiCollectionName and nameList are provided, fetch criteria and build filter
 	if apiCollectionName != "" && len(nameList) > 0 {
-		criteriaMap, err := m.GetCriteriaByCollections(m.Ctx, apiCollectionName, nameList)
+		criteriaMap, err := m.GetCriteriaByCollections(ctx, apiCollectionName, nameList)
 		if err != nil {
 			m.Logger.Errorf("failed to get criteria by collections: %v", err)
-			return nil, fmt.Errorf("failed to get criteria by collections: %w", err)
+			return fmt.Errorf("failed to get criteria by collections: %w", err)
 		}
 
 		var allCriteria []FilterCriteria
@@ -38,154 +57,174 @@ func (m *Manager) findApiOperationDocuments(eventCollectionName, apiCollectionNa
 			criteriaFilter, err := buildMongoFilterCriteria(allCriteria)
 			if err != nil {
 				m.Logger.Errorf("failed to build mongo query for collection filter criteria: %v", err)
-				return nil, fmt.Errorf("failed to build mongo query for collection filter criteria: %w", err)
+				return fmt.Errorf("failed to build mongo query for collection filter criteria: %w", err)
 			}
 			filter = append(filter, bson.E{Key: "$and", Value: criteriaFilter})
 		}
 	}
 
-	projection := bson.D{
-		{Key: "_id", Value: 0},
-		{Key: "cluster_name", Value: 1},
-		{Key: "api_event.http.request.headers.:authority", Value: 1},
-		{Key: "api_event.http.request.method", Value: 1},
-		{Key: "api_event.http.request.path", Value: 1},
-		{Key: "api_event.http.response.status_code", Value: 1},
-		{Key: "api_event.count", Value: 1},
+	// The events are grouped by API and response status in the database, only
+	// the groups are sent to the client.
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "cluster_name", Value: "$cluster_name"},
+				{Key: "authority", Value: "$api_event.http.request.headers.:authority"},
+				{Key: "method", Value: "$api_event.http.request.method"},
+				{Key: "path", Value: "$api_event.http.request.path"},
+				{Key: "status_code", Value: toLong("$api_event.http.response.status_code", nil)},
+				{Key: "content_type", Value: "$api_event.http.response.headers.content-type"},
+			}},
+			// Documents without a count stand for a single event.
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: toLong("$api_event.count", 1)}}},
+			{Key: "first_seen", Value: bson.D{{Key: "$min", Value: "$" + m.Cfg.Database.TimestampField}}},
+			{Key: "last_seen", Value: bson.D{{Key: "$max", Value: "$" + m.Cfg.Database.TimestampField}}},
+		}}},
 	}
 
-	findOpts := &options.FindOptions{
-		Projection: &projection,
-	}
+	aggregateOpts := options.Aggregate().
+		SetAllowDiskUse(true).
+		SetBatchSize(m.Cfg.Processing.BatchSize)
 
-	cursor, err := m.DBHandler.Database.Collection(eventCollectionName).Find(m.Ctx, filter, findOpts)
+	cursor, err := m.DBHandler.Database.Collection(eventCollectionName).Aggregate(ctx, pipeline, aggregateOpts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find documents: %w", err)
+		return fmt.Errorf("failed to aggregate documents: %w", err)
 	}
 	defer func() {
-		if cerr := cursor.Close(m.Ctx); cerr != nil {
+		if cerr := cursor.Close(ctx); cerr != nil {
 			m.Logger.Errorf("failed to close cursor: %v", cerr)
 		}
 	}()
 
-	apiEvents := hashset.New()
-	for cursor.Next(m.Ctx) {
-		var doc bson.M
-		if err := cursor.Decode(&doc); err != nil {
+	emitted := 0
+	for cursor.Next(ctx) {
+		var group apiEventGroup
+		if err := cursor.Decode(&group); err != nil {
 			m.Logger.Errorf("failed to decode document: %v", err)
 			continue
 		}
 
-		rcVal, ok := getNested(doc, "api_event", "http", "response", "status_code")
-		if !ok {
-			continue
-		}
-		responseCode, ok := toInt(rcVal)
-		if !ok {
+		if !group.ID.StatusCode.valid {
 			// can't interpret response code as int -> skip
 			continue
 		}
 
-		clusterName, _ := getString(doc, "cluster_name")
-		serviceName, _ := getNestedString(doc, "api_event", "http", "request", "headers", ":authority")
-		requestMethod, _ := getNestedString(doc, "api_event", "http", "request", "method")
-		requestPath, _ := getNestedString(doc, "api_event", "http", "request", "path")
-
-		occVal, _ := getNested(doc, "api_event", "count")
-		occurrences, _ := toInt(occVal)
-
-		apiEvents.Add(apievent.ApiEvent{
-			ClusterName:   clusterName,
-			ServiceName:   serviceName,
-			RequestMethod: requestMethod,
-			RequestPath:   requestPath,
-			ResponseCode:  responseCode,
-			Occurrences:   occurrences,
-		})
+		if err := emit(apievent.ApiEvent{
+			ClusterName:   group.ID.ClusterName,
+			ServiceName:   group.ID.Authority,
+			RequestMethod: group.ID.Method,
+			RequestPath:   group.ID.Path,
+			ResponseCode:  int(group.ID.StatusCode.value),
+			ContentType:   group.ID.ContentType,
+			Occurrences:   int(group.Count.value),
+			FirstSeen:     group.FirstSeen.value,
+			LastSeen:      group.LastSeen.value,
+		}); err != nil {
+			return err
+		}
+		emitted++
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to iterate documents: %w", err)
 	}
 
-	if apiEvents.Size() == 0 {
+	if emitted == 0 {
 		clusterInfo := fmt.Sprintf("clusterID: `%d`", clusterId)
 		if clusterId == 0 {
 			clusterInfo = "all clusters"
 		}
 		m.Logger.Warnf("no documents found in `%s` collection for %s", eventCollectionName, clusterInfo)
-		return apiEvents, nil
 	}
 
-	return apiEvents, nil
+	return nil
 }
 
-// helper: safely get a top-level string value from bson.M
-func getString(m bson.M, key string) (string, bool) {
-	if v, ok := m[key]; ok && v != nil {
-		if s, ok := v.(string); ok {
-			return s, true
-		}
+// timeWindowFilter matches the dates in the window, the until bound excluded.
+func timeWindowFilter(window config.TimeWindow) bson.D {
+	predicate := bson.D{}
+	if !window.Since.IsZero() {
+		predicate = append(predicate, bson.E{Key: "$gte", Value: window.Since})
+	}
+	if !window.Until.IsZero() {
+		predicate = append(predicate, bson.E{Key: "$lt", Value: window.Until})
 	}
-	return "", false
+	return predicate
 }
 
-// helper: navigate nested maps and return value if exists
-func getNested(m bson.M, path ...string) (interface{}, bool) {
-	var cur interface{} = m
-	for _, p := range path {
-		if curMap, ok := cur.(bson.M); ok {
-			if v, exists := curMap[p]; exists {
-				cur = v
-				continue
-			}
-			// try map[string]interface{} as some decoders may produce that
-			if altMap, ok := interface{}(curMap).(map[string]interface{}); ok {
-				if v, exists := altMap[p]; exists {
-					cur = v
-					continue
-				}
-			}
-			return nil, false
-		}
+// toLong converts the field to a 64-bit integer, numeric strings included. The
+// fallback is used for missing values and values that can't be converted.
+func toLong(field string, fallback any) bson.D {
+	return bson.D{{Key: "$convert", Value: bson.D{
+		{Key: "input", Value: field},
+		{Key: "to", Value: "long"},
+		{Key: "onError", Value: fallback},
+		{Key: "onNull", Value: fallback},
+	}}}
+}
 
-		// If current isn't a map, cannot descend further
-		return nil, false
-	}
-	return cur, true
+// apiEventGroup holds the API events grouped by API and response status.
+type apiEventGroup struct {
+	ID struct {
+		ClusterName string       `bson:"cluster_name"`
+		Authority   string       `bson:"authority"`
+		Method      string       `bson:"method"`
+		Path        string       `bson:"path"`
+		StatusCode  numericValue `bson:"status_code"`
+		ContentType string       `bson:"content_type"`
+	} `bson:"_id"`
+	Count     numericValue `bson:"count"`
+	FirstSeen timeValue    `bson:"first_seen"`
+	LastSeen  timeValue    `bson:"last_seen"`
 }
 
-func getNestedString(m bson.M, path ...string) (string, bool) {
-	v, ok := getNested(m, path...)
-	if !ok || v == nil {
-		return "", false
-	}
-	if s, ok := v.(string); ok {
-		return s, true
-	}
-	return "", false
+// numericValue decodes the numeric BSON types, as well as numeric strings.
+// Values that can't be interpreted as an integer are left invalid.
+type numericValue struct {
+	value int64
+	valid bool
 }
 
-// toInt attempts to convert various numeric BSON types to an int (int64/int32/float64/string)
-func toInt(v interface{}) (int, bool) {
-	if v == nil {
-		return 0, false
+func (n *numericValue) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: t, Value: data}
+	if i, ok := value.AsInt64OK(); ok {
+		*n = numericValue{value: i, valid: true}
+	} else if s, ok := value.StringValueOK(); ok {
+		if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			*n = numericValue{value: i, valid: true}
+		}
 	}
-	switch val := v.(type) {
-	case int:
-		return val, true
-	case int32:
-		return int(val), true
-	case int64:
-		return int(val), true
-	case float64:
-		return int(val), true
-	case string:
-		var n int
-		_, err := fmt.Sscanf(val, "%d", &n)
-		if err == nil {
-			return n, true
+	return nil
+}
+
+// timeValue decodes BSON dates and timestamps, epoch numbers in seconds or
+// milliseconds, and RFC 3339 strings. Other values are left as the zero time.
+type timeValue struct {
+	value time.Time
+}
+
+// epochMillisThreshold separates the epochs in milliseconds from the ones in
+// seconds, it is in the year 33658 in seconds.
+const epochMillisThreshold = 1e12
+
+func (v *timeValue) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: t, Value: data}
+	if datetime, ok := value.DateTimeOK(); ok {
+		v.value = time.UnixMilli(datetime).UTC()
+	} else if seconds, _, ok := value.TimestampOK(); ok {
+		v.value = time.Unix(int64(seconds), 0).UTC()
+	} else if epoch, ok := value.AsInt64OK(); ok {
+		if epoch >= epochMillisThreshold {
+			v.value = time.UnixMilli(epoch).UTC()
+		} else {
+			v.value = time.Unix(epoch, 0).UTC()
+		}
+	} else if s, ok := value.StringValueOK(); ok {
+		if parsed, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			v.value = parsed.UTC()
 		}
-		return 0, false
-	default:
-		return 0, false
 	}
+	return nil
 }
 
 // FilterCriteria defines a condition and operator for Mongo query filtering.
@@ -3,64 +3,115 @@ package core
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/5gsec/api-speculator/internal/config"
 )
 
-func TestGetNestedAndGetNestedString(t *testing.T) {
-	doc := bson.M{
-		"level1": bson.M{
-			"level2": bson.M{
-				"str": "value",
-				"num": int32(42),
-			},
-			"plain": "top",
+func TestDecodeApiEventGroup(t *testing.T) {
+	firstSeen := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	raw, err := bson.Marshal(bson.M{
+		"_id": bson.M{
+			"cluster_name": "prod",
+			"authority":    "orders",
+			"method":       "GET",
+			"path":         "/orders/1",
+			"status_code":  int64(200),
 		},
+		"count":      int64(3),
+		"first_seen": primitive.NewDateTimeFromTime(firstSeen),
+		"last_seen":  nil,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
 
-	// existing nested
-	if v, ok := getNested(doc, "level1", "level2", "str"); !ok || v != "value" {
-		t.Fatalf("expected nested string 'value', got %#v (ok=%v)", v, ok)
+	var group apiEventGroup
+	if err := bson.Unmarshal(raw, &group); err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-
-	// getNestedString existing
-	if s, ok := getNestedString(doc, "level1", "plain"); !ok || s != "top" {
-		t.Fatalf("expected nested string 'top', got %#v (ok=%v)", s, ok)
+	if group.ID.ClusterName != "prod" || group.ID.Authority != "orders" ||
+		group.ID.Method != "GET" || group.ID.Path != "/orders/1" {
+		t.Fatalf("unexpected group: %#v", group)
+	}
+	if group.ID.StatusCode != (numericValue{value: 200, valid: true}) {
+		t.Fatalf("expected status code 200, got %#v", group.ID.StatusCode)
 	}
+	if group.Count != (numericValue{value: 3, valid: true}) {
+		t.Fatalf("expected count 3, got %#v", group.Count)
+	}
+	if !group.FirstSeen.value.Equal(firstSeen) || !group.LastSeen.value.IsZero() {
+		t.Fatalf("unexpected first/last seen: %v/%v", group.FirstSeen.value, group.LastSeen.value)
+	}
+}
 
-	// non-existing path
-	if _, ok := getNested(doc, "level1", "missing"); ok {
-		t.Fatalf("expected missing path to be false")
+func TestTimeValueVariousTypes(t *testing.T) {
+	want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name  string
+		input interface{}
+		want  time.Time
+	}{
+		{"datetime", primitive.NewDateTimeFromTime(want), want},
+		{"timestamp", primitive.Timestamp{T: uint32(want.Unix())}, want},
+		{"epochSeconds", want.Unix(), want},
+		{"epochMillis", want.UnixMilli(), want},
+		{"rfc3339", "2024-05-01T12:00:00+02:00", want},
+		{"badString", "yesterday", time.Time{}},
+		{"nil", nil, time.Time{}},
 	}
 
-	// wrong type for getNestedString
-	if s, ok := getNestedString(doc, "level1", "level2", "num"); ok || s != "" {
-		t.Fatalf("expected non-string to return false and empty string, got s=%q ok=%v", s, ok)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			raw, err := bson.Marshal(bson.M{"value": tc.input})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var doc struct {
+				Value timeValue `bson:"value"`
+			}
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !doc.Value.value.Equal(tc.want) {
+				t.Fatalf("decoding %#v = %v, want %v", tc.input, doc.Value.value, tc.want)
+			}
+		})
 	}
 }
 
-func TestToIntVariousTypes(t *testing.T) {
+func TestNumericValueVariousTypes(t *testing.T) {
 	cases := []struct {
-		name   string
-		input  interface{}
-		want   int
-		wantOk bool
+		name  string
+		input interface{}
+		want  numericValue
 	}{
-		{"int", int(7), 7, true},
-		{"int32", int32(8), 8, true},
-		{"int64", int64(9), 9, true},
-		{"float64", float64(10.0), 10, true},
-		{"numericString", "123", 123, true},
-		{"badString", "abc", 0, false},
-		{"nil", nil, 0, false},
-		{"unsupported", bson.M{}, 0, false},
+		{"int32", int32(8), numericValue{value: 8, valid: true}},
+		{"int64", int64(9), numericValue{value: 9, valid: true}},
+		{"float64", float64(10.0), numericValue{value: 10, valid: true}},
+		{"numericString", "123", numericValue{value: 123, valid: true}},
+		{"badString", "abc", numericValue{}},
+		{"nil", nil, numericValue{}},
+		{"unsupported", bson.M{}, numericValue{}},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			got, ok := toInt(tc.input)
-			if ok != tc.wantOk || got != tc.want {
-				t.Fatalf("toInt(%#v) = (%d, %v), want (%d, %v)", tc.input, got, ok, tc.want, tc.wantOk)
+			raw, err := bson.Marshal(bson.M{"value": tc.input})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var doc struct {
+				Value numericValue `bson:"value"`
+			}
+			if err := bson.Unmarshal(raw, &doc); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if doc.Value != tc.want {
+				t.Fatalf("decoding %#v = %#v, want %#v", tc.input, doc.Value, tc.want)
 			}
 		})
 	}
@@ -142,3 +193,20 @@ func TestBuildMongoFilterCriteria_SingleHostnameEq(t *testing.T) {
 		t.Fatalf("unexpected $in value type: %T %#v", v, v)
 	}
 }
+
+func TestTimeWindowFilter(t *testing.T) {
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(7 * 24 * time.Hour)
+
+	got := timeWindowFilter(config.TimeWindow{Since: since, Until: until})
+	want := bson.D{{Key: "$gte", Value: since}, {Key: "$lt", Value: until}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("timeWindowFilter = %#v, want %#v", got, want)
+	}
+
+	got = timeWindowFilter(config.TimeWindow{Since: since})
+	want = bson.D{{Key: "$gte", Value: since}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("timeWindowFilter = %#v, want %#v", got, want)
+	}
+}
@@ -5,9 +5,12 @@ package core
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 
+	"github.com/5gsec/api-speculator/internal/apievent"
 	"github.com/5gsec/api-speculator/internal/config"
 	"github.com/5gsec/api-speculator/internal/database"
 	"github.com/5gsec/api-speculator/internal/util"
@@ -18,6 +21,9 @@ type Manager struct {
 	Logger    *zap.SugaredLogger
 	DBHandler *database.Handler
 	Cfg       config.Configuration
+
+	// ignoreRules are compiled from the configuration.
+	ignoreRules []*ignoreRule
 }
 
 func (m *Manager) close() {
@@ -28,7 +34,10 @@ func (m *Manager) close() {
 	}
 }
 
-func Run(ctx context.Context, configFilePath string) {
+// Run scans the configured traffic against the configured specs and exports
+// the findings. An error is returned if the scan failed or is incomplete, e.g.
+// because a spec could not be loaded.
+func Run(ctx context.Context, configFilePath string) error {
 	mgr := &Manager{
 		Ctx:    ctx,
 		Logger: util.GetLogger(),
@@ -39,40 +48,97 @@ func Run(ctx context.Context, configFilePath string) {
 
 	cfg, err := config.New(configFilePath, mgr.Logger)
 	if err != nil {
-		mgr.Logger.Error(err)
-		return
+		return err
 	}
 	mgr.Cfg = cfg
+	if mgr.ignoreRules, err = newIgnoreRules(mgr.Cfg.Ignore); err != nil {
+		return err
+	}
 
-	dbHandler, err := database.New(mgr.Ctx, mgr.Cfg.Database)
+	var report apiReport
+	specs, specErrs := mgr.buildSpecs()
+	report.Errors = append(report.Errors, specLoadErrors(specErrs)...)
+
+	source, err := mgr.newEventSource()
 	if err != nil {
-		mgr.Logger.Error(err)
-		return
+		return err
 	}
-	mgr.DBHandler = dbHandler
 
-	collectionName := mgr.Cfg.Database.Collection
-	clusterId := mgr.Cfg.Environment.ClusterId
-	apiCollectionName := mgr.Cfg.APICollections.CollectionTemplate
-	nameList := mgr.Cfg.APICollections.NameList
+	scan := mgr.newScan(specs)
+	if err := source.Stream(mgr.Ctx, func(event apievent.ApiEvent) error {
+		scan.add(event)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to read API events: %w", err)
+	}
+	scan.report(&report)
+	if report.Stats.Events == 0 && len(specErrs) == 0 {
+		return nil
+	}
+	mgr.applySuppressions(&report, time.Now())
 
-	events, err := mgr.findApiOperationDocuments(collectionName, apiCollectionName, clusterId, nameList)
-	if err != nil {
-		mgr.Logger.Errorf("failed to find documents: %v", err)
-		return
+	if err := mgr.exportJsonReport(mgr.Cfg.Exporter.JsonReportFilePath, report); err != nil {
+		return err
 	}
-	if events.Size() == 0 {
-		return
+	mgr.Logger.Infof("successfully generated `%s` JSON report", mgr.Cfg.Exporter.JsonReportFilePath)
+
+	if len(specErrs) > 0 {
+		return fmt.Errorf("failed to load %d spec(s), see the `%s` JSON report", len(specErrs), mgr.Cfg.Exporter.JsonReportFilePath)
 	}
+	return nil
+}
 
-	model, _ := mgr.buildModel(mgr.Cfg.OpenAPISpec)
-	trie := mgr.buildTrie(model)
+// specLoadErrors converts the errors of the specs that failed to load into
+// report entries.
+func specLoadErrors(specErrs []*SpecLoadError) []ScanError {
+	var scanErrs []ScanError
+	for _, specErr := range specErrs {
+		scanErrs = append(scanErrs, ScanError{
+			Type:    ScanErrorSpecLoadFailed,
+			Spec:    specErr.Spec,
+			Source:  specErr.Source,
+			Message: specErr.Err.Error(),
+		})
+	}
+	return scanErrs
+}
 
-	shadowApis, zombieApis := mgr.findShadowAndZombieApi(trie, events, model)
-	orphanApis := mgr.findOrphanApi(events, model)
-	if err := mgr.exportJsonReport(mgr.Cfg.Exporter.JsonReportFilePath, shadowApis, zombieApis, orphanApis); err != nil {
-		mgr.Logger.Error(err)
-		return
+// newEventSource creates the configured traffic source, connecting to the
+// database if needed.
+func (m *Manager) newEventSource() (apievent.EventSource, error) {
+	switch m.Cfg.Traffic.Source {
+	case config.TrafficSourceJSONL:
+		return &apievent.JSONLSource{Path: m.Cfg.Traffic.Path}, nil
+	case config.TrafficSourceHAR:
+		return &apievent.HARSource{Path: m.Cfg.Traffic.Path}, nil
+	case config.TrafficSourceEnvoy:
+		return &apievent.EnvoySource{
+			Path:     m.Cfg.Traffic.Path,
+			Format:   m.Cfg.Traffic.Envoy.Format,
+			JSONKeys: apievent.EnvoyJSONKeys(m.Cfg.Traffic.Envoy.JSONKeys),
+		}, nil
+	case config.TrafficSourceNginx:
+		return &apievent.NginxSource{
+			Path:        m.Cfg.Traffic.Path,
+			Format:      m.Cfg.Traffic.Nginx.Format,
+			ServiceName: m.Cfg.Traffic.Nginx.ServiceName,
+		}, nil
+	case config.TrafficSourceOTel:
+		return &apievent.OTelSource{
+			Path:     m.Cfg.Traffic.Path,
+			Listen:   m.Cfg.Traffic.OTel.Listen,
+			Duration: m.Cfg.Traffic.OTel.Duration,
+		}, nil
+	case config.TrafficSourcePcap:
+		return &apievent.PcapSource{Path: m.Cfg.Traffic.Path}, nil
+	case config.TrafficSourceProxy:
+		return nil, fmt.Errorf("the `%s` traffic source is only available with `speculator proxy`", config.TrafficSourceProxy)
+	default:
+		dbHandler, err := database.New(m.Ctx, m.Cfg.Database)
+		if err != nil {
+			return nil, err
+		}
+		m.DBHandler = dbHandler
+		return &mongoEventSource{mgr: m}, nil
 	}
-	mgr.Logger.Infof("successfully generated `%s` JSON report", mgr.Cfg.Exporter.JsonReportFilePath)
 }
@@ -5,124 +5,170 @@ package core
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 
-	"github.com/emirpasic/gods/sets/hashset"
-	"github.com/pb33f/libopenapi"
-	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
-
 	"github.com/5gsec/api-speculator/internal/apievent"
 	"github.com/5gsec/api-speculator/internal/apispec"
+	"github.com/5gsec/api-speculator/internal/inventory"
 	"github.com/5gsec/api-speculator/internal/pathtrie"
+	"github.com/5gsec/api-speculator/internal/util"
 )
 
-func (m *Manager) findShadowAndZombieApi(trie pathtrie.PathTrie, events *hashset.Set, model *libopenapi.DocumentModel[v3.Document]) ([]API, []API) {
-	var shadowApis []API
-	var zombieApis []API
-
-	for _, value := range events.Values() {
-		event, ok := value.(apievent.ApiEvent)
-		if !ok {
-			m.Logger.Warnf("failed to parse endpoint `%v`", value)
-			continue
+// evaluateEvent returns the shadow API and the zombie API the event hits, nil
+// if it doesn't hit any, as well as the spec operation it exercises.
+func evaluateEvent(trie pathtrie.PathTrie, event apievent.ApiEvent) (*API, *API, *inventory.Operation) {
+	requestPath, pathItem, found, mismatch := lookupEvent(trie, event)
+	operation := getOperation(pathItem, event.RequestMethod)
+
+	var shadowApi, zombieApi *API
+	shadowCategory := ""
+	if mismatch != nil {
+		shadowCategory = ShadowCategoryParameterMismatch
+	} else if !found {
+		shadowCategory = ShadowCategoryUnknownPath
+	} else if operation == nil {
+		// The path is documented, but not for the observed method.
+		shadowCategory = ShadowCategoryUndocumentedMethod
+	}
+	if shadowCategory != "" {
+		shadowApi = &API{
+			ClusterName:   event.ClusterName,
+			ServiceName:   event.ServiceName,
+			RequestMethod: event.RequestMethod,
+			RequestPath:   requestPath,
+			Occurrences:   event.Occurrences,
+			Category:      shadowCategory,
+			FirstSeen:     event.FirstSeen,
+			LastSeen:      event.LastSeen,
 		}
-		requestPath, _ := apispec.GetPathAndQuery(event.RequestPath)
-
-		// Skip static assets and root endpoint
-		if requestPath == "/" ||
-			strings.HasPrefix(requestPath, "/assets") ||
-			strings.HasPrefix(requestPath, "/site") ||
-			strings.HasPrefix(requestPath, "/sites") ||
-			strings.HasPrefix(requestPath, "env") ||
-			strings.HasSuffix(requestPath, "env") ||
-			strings.HasSuffix(requestPath, "png") ||
-			strings.HasSuffix(requestPath, "svg") ||
-			strings.HasSuffix(requestPath, "gif") ||
-			strings.HasSuffix(requestPath, "js") {
-			continue
+		if mismatch != nil {
+			shadowApi.SpecPath = mismatch.specPath
+			shadowApi.Detail = mismatch.Error()
 		}
+	}
 
-		_, _, found := trie.GetPathAndValue(requestPath)
-		if !found {
-			if !contains(shadowApis, event) {
-				shadowApis = append(shadowApis, API{
-					ClusterName:   event.ClusterName,
-					ServiceName:   event.ServiceName,
-					RequestMethod: event.RequestMethod,
-					RequestPath:   requestPath,
-					Occurrences:   event.Occurrences,
-				})
-			}
+	// Only the operation for the observed method decides whether the event
+	// hits a deprecated API, other operations on the same path don't matter.
+	if operation != nil && operation.Deprecated {
+		zombieApi = &API{
+			ClusterName:   event.ClusterName,
+			ServiceName:   event.ServiceName,
+			RequestMethod: event.RequestMethod,
+			RequestPath:   requestPath,
+			SpecPath:      operation.PathTemplate,
+			Occurrences:   event.Occurrences,
+			FirstSeen:     event.FirstSeen,
+			LastSeen:      event.LastSeen,
 		}
+	}
 
-		currPathValue, found := model.Model.Paths.PathItems.Get(requestPath)
-		if found {
-			for operation := currPathValue.GetOperations().First(); operation != nil; operation = operation.Next() {
-				if operation.Value().Deprecated != nil && *operation.Value().Deprecated {
-					if !contains(zombieApis, event) {
-						zombieApis = append(zombieApis, API{
-							ClusterName:   event.ClusterName,
-							ServiceName:   event.ServiceName,
-							RequestMethod: event.RequestMethod,
-							RequestPath:   requestPath,
-							Occurrences:   event.Occurrences,
-						})
-					}
-				}
-			}
+	return shadowApi, zombieApi, operation
+}
+
+// lookupEvent returns the path of the event's request and the trie value of the
+// spec path it matches. The route template of the event is authoritative when
+// it matches a spec path, the request path is matched otherwise. Spec paths
+// whose path parameter schemas reject the request path values don't match, the
+// mismatch of the most accurate of them is returned if no other spec path
+// matches.
+func lookupEvent(trie pathtrie.PathTrie, event apievent.ApiEvent) (string, any, bool, *paramMismatch) {
+	requestPath, _ := apispec.GetPathAndQuery(event.RequestPath)
+	if event.RouteTemplate != "" {
+		if _, value, found := trie.GetPathAndValue(event.RouteTemplate); found {
+			return requestPath, value, true, nil
 		}
 	}
 
-	return shadowApis, zombieApis
+	segments := strings.Split(requestPath, trie.PathSeparator)
+	_, value, found := trie.GetPathAndValueFunc(requestPath, func(fullPath string, value any) bool {
+		return validatePathParams(fullPath, value, segments, event.RequestMethod, trie.PathSeparator) == nil
+	})
+	if found {
+		return requestPath, value, true, nil
+	}
+
+	fullPath, value, found := trie.GetPathAndValue(requestPath)
+	if !found {
+		return requestPath, nil, false, nil
+	}
+	return requestPath, nil, false, validatePathParams(fullPath, value, segments, event.RequestMethod, trie.PathSeparator)
 }
 
-func (m *Manager) findOrphanApi(events *hashset.Set, model *libopenapi.DocumentModel[v3.Document]) []API {
-	var orphanApis []API
+// paramMismatch describes a path parameter value its schema rejects.
+type paramMismatch struct {
+	specPath string
+	param    string
+	value    string
+	err      error
+}
 
-	traffickedEndpointsWithReqMethodAndPathOnly := make(map[string]struct{}, events.Size())
-	for _, value := range events.Values() {
-		event, ok := value.(apievent.ApiEvent)
-		if !ok {
-			m.Logger.Warnf("failed to parse endpoint `%v`", value)
-			continue
-		}
+func (m *paramMismatch) Error() string {
+	return fmt.Sprintf("path parameter `%s` %v, got `%s`", m.param, m.err, m.value)
+}
 
-		requestPath, _ := apispec.GetPathAndQuery(event.RequestPath)
-		requestPath = apispec.UnifyParameterizedPathIfApplicable(requestPath, false)
-		requestMethod := strings.ToUpper(event.RequestMethod)
-		key := fmt.Sprintf("%v/%v", requestMethod, requestPath)
+// validatePathParams validates the values of the request path segments against
+// the schemas of the path parameters of the spec path stored in a trie node.
+// The operation documented for the request method is used, any operation of the
+// path if there is none.
+func validatePathParams(fullPath string, value any, segments []string, requestMethod, pathSeparator string) *paramMismatch {
+	pathItem, ok := value.(*inventory.PathItem)
+	if !ok {
+		return nil
+	}
+	operations := pathItem.Operations
+	if operation := pathItem.GetOperation(requestMethod); operation != nil {
+		operations = []*inventory.Operation{operation}
+	}
 
-		if _, exists := traffickedEndpointsWithReqMethodAndPathOnly[key]; !exists {
-			traffickedEndpointsWithReqMethodAndPathOnly[key] = struct{}{}
+	templateSegments := strings.Split(fullPath, pathSeparator)
+	var firstMismatch *paramMismatch
+	for _, operation := range operations {
+		mismatch := validateOperationPathParams(operation, templateSegments, segments)
+		if mismatch == nil {
+			return nil
+		}
+		if firstMismatch == nil {
+			firstMismatch = mismatch
 		}
 	}
+	return firstMismatch
+}
 
-	for pathItems := model.Model.Paths.PathItems.First(); pathItems != nil; pathItems = pathItems.Next() {
-		for operations := pathItems.Value().GetOperations().First(); operations != nil; operations = operations.Next() {
-			requestPath := apispec.UnifyParameterizedPathIfApplicable(pathItems.Key(), true)
-			requestMethod := strings.ToUpper(operations.Key())
-			key := fmt.Sprintf("%v/%v", requestMethod, requestPath)
-
-			if _, exists := traffickedEndpointsWithReqMethodAndPathOnly[key]; !exists {
-				// This spec endpoint didn't receive traffic.
-				orphanApis = append(orphanApis, API{
-					RequestMethod: requestMethod,
-					RequestPath:   requestPath,
-				})
+func validateOperationPathParams(operation *inventory.Operation, templateSegments, segments []string) *paramMismatch {
+	for idx, segment := range templateSegments {
+		if !util.IsPathParam(segment) || idx >= len(segments) {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(segment, util.ParamPrefix), util.ParamSuffix)
+		for _, param := range operation.Params {
+			if param.In != "path" || param.Name != name {
+				continue
+			}
+			value, err := url.PathUnescape(segments[idx])
+			if err != nil {
+				value = segments[idx]
+			}
+			if err := param.Schema.Validate(value); err != nil {
+				return &paramMismatch{specPath: operation.PathTemplate, param: name, value: value, err: err}
 			}
 		}
 	}
+	return nil
+}
 
-	return orphanApis
+// operationKey identifies the requests sent with the same method to the same
+// path.
+func operationKey(requestMethod, specPath string) string {
+	return fmt.Sprintf("%v %v", strings.ToUpper(requestMethod), specPath)
 }
 
-func contains(apis []API, currEvent apievent.ApiEvent) bool {
-	for _, api := range apis {
-		if api.RequestPath == currEvent.RequestPath &&
-			api.RequestMethod == currEvent.RequestMethod &&
-			api.ServiceName == currEvent.ServiceName {
-			return true
-		}
+// getOperation returns the operation documented for the given request method on
+// the path item stored in a trie node, nil if there is no such operation.
+func getOperation(value any, requestMethod string) *inventory.Operation {
+	pathItem, ok := value.(*inventory.PathItem)
+	if !ok {
+		return nil
 	}
-	return false
+	return pathItem.GetOperation(requestMethod)
 }
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/config"
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+func newTestInventory() *inventory.Inventory {
+	return &inventory.Inventory{
+		PathItems: []*inventory.PathItem{
+			{
+				PathTemplate: "/users/{id}",
+				Operations: []*inventory.Operation{
+					{Method: "GET", PathTemplate: "/users/{id}"},
+				},
+			},
+			{
+				PathTemplate: "/orders/{id}",
+				Operations: []*inventory.Operation{
+					{Method: "GET", PathTemplate: "/orders/{id}", Deprecated: true},
+					{Method: "POST", PathTemplate: "/orders/{id}"},
+				},
+			},
+		},
+	}
+}
+
+func newTestManager() *Manager {
+	return &Manager{Logger: zap.NewNop().Sugar()}
+}
+
+// scanEvents scans the events against a single spec documenting the inventory.
+func scanEvents(m *Manager, inv *inventory.Inventory, events ...apievent.ApiEvent) apiReport {
+	spec := &apiSpec{cfg: config.Spec{Name: "test"}, inventory: inv, trie: m.buildTrie(inv, config.PathPrefixes{})}
+	scan := m.newScan([]*apiSpec{spec})
+	for _, event := range events {
+		scan.add(event)
+	}
+
+	var report apiReport
+	scan.report(&report)
+	return report
+}
+
+func TestScan_ShadowCategories(t *testing.T) {
+	report := scanEvents(newTestManager(), newTestInventory(),
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/42", ResponseCode: 200},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "DELETE", RequestPath: "/users/42", ResponseCode: 204},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts", ResponseCode: 200},
+	)
+	require.Len(t, report.ShadowAPIs, 2)
+
+	categories := map[string]string{}
+	for _, api := range report.ShadowAPIs {
+		categories[api.RequestMethod+" "+api.RequestPath] = api.Category
+	}
+	assert.Equal(t, map[string]string{
+		"DELETE /users/42": ShadowCategoryUndocumentedMethod,
+		"GET /carts":       ShadowCategoryUnknownPath,
+	}, categories)
+}
+
+func TestScan_Zombie(t *testing.T) {
+	report := scanEvents(newTestManager(), newTestInventory(),
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/orders/42", ResponseCode: 200},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "POST", RequestPath: "/orders/43", ResponseCode: 201},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/42", ResponseCode: 200},
+	)
+	require.Len(t, report.ZombieAPIs, 1)
+	assert.Equal(t, "GET", report.ZombieAPIs[0].RequestMethod)
+	assert.Equal(t, "/orders/42", report.ZombieAPIs[0].RequestPath)
+	assert.Equal(t, "/orders/{id}", report.ZombieAPIs[0].SpecPath)
+}
+
+func TestScan_Orphan(t *testing.T) {
+	report := scanEvents(newTestManager(), newTestInventory(),
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/42", ResponseCode: 200},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "post", RequestPath: "/orders/abc?dryRun=true", ResponseCode: 201},
+	)
+	assert.Equal(t, []API{{RequestMethod: "GET", RequestPath: "/orders/{id}", Spec: "test"}}, report.OrphanAPIs)
+}
+
+func TestScan_OrphanRouteTemplate(t *testing.T) {
+	inv := newTestInventory()
+	inv.PathItems = append(inv.PathItems, &inventory.PathItem{
+		PathTemplate: "/users/me",
+		Operations: []*inventory.Operation{
+			{Method: "GET", PathTemplate: "/users/me"},
+		},
+	})
+
+	// The route the server reported wins over the more specific request path.
+	report := scanEvents(newTestManager(), inv,
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/me", RouteTemplate: "/users/{userId}"},
+	)
+	assert.Contains(t, report.OrphanAPIs, API{RequestMethod: "GET", RequestPath: "/users/me", Spec: "test"})
+	assert.NotContains(t, report.OrphanAPIs, API{RequestMethod: "GET", RequestPath: "/users/{id}", Spec: "test"})
+}
+
+func TestScan_ParameterTypeMismatch(t *testing.T) {
+	idParam := inventory.Param{Name: "id", In: "path", Required: true, Schema: inventory.Schema{Type: "integer"}}
+	inv := &inventory.Inventory{
+		PathItems: []*inventory.PathItem{
+			{
+				PathTemplate: "/users/{id}",
+				Operations: []*inventory.Operation{
+					{Method: "GET", PathTemplate: "/users/{id}", Params: []inventory.Param{idParam}},
+				},
+			},
+			{
+				PathTemplate: "/users/{id}/orders/{orderId}",
+				Operations: []*inventory.Operation{
+					{Method: "GET", PathTemplate: "/users/{id}/orders/{orderId}", Params: []inventory.Param{
+						idParam,
+						{Name: "orderId", In: "path", Required: true, Schema: inventory.Schema{Type: "string", Format: "uuid"}},
+					}},
+				},
+			},
+		},
+	}
+
+	report := scanEvents(newTestManager(), inv,
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/42"},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/export?format=csv"},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/42/orders/latest"},
+	)
+	assert.Equal(t, []API{
+		{
+			ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/export", SpecPath: "/users/{id}", Occurrences: 1,
+			Category: ShadowCategoryParameterMismatch, Spec: "test", Detail: "path parameter `id` expects an integer, got `export`",
+		},
+		{
+			ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/42/orders/latest", SpecPath: "/users/{id}/orders/{orderId}", Occurrences: 1,
+			Category: ShadowCategoryParameterMismatch, Spec: "test", Detail: "path parameter `orderId` expects a uuid, got `latest`",
+		},
+	}, report.ShadowAPIs)
+	// Only the valid request exercises its operation.
+	assert.Equal(t, []API{{RequestMethod: "GET", RequestPath: "/users/{id}/orders/{orderId}", Spec: "test"}}, report.OrphanAPIs)
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"fmt"
+	"mime"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/config"
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+// ignorePresets are the built-in ignore rules, by preset name. The rules of a
+// preset are reported under the preset name.
+var ignorePresets = map[string][]config.IgnoreRule{
+	"static-assets": {
+		{Paths: []string{"/assets/**", "/static/**", "/favicon.ico"}},
+		{PathRegex: []string{`(?i)\.(css|js|mjs|map|png|jpe?g|gif|svg|ico|webp|woff2?|ttf|eot)$`}},
+		{ContentTypes: []string{"image/*", "font/*", "text/css", "text/javascript", "application/javascript"}},
+	},
+	"health-checks": {
+		{Paths: []string{"/health", "/healthz", "/livez", "/readyz", "/ping", "/metrics"}, Methods: []string{"GET", "HEAD"}},
+	},
+	"cors-preflight": {
+		{Methods: []string{"OPTIONS"}},
+	},
+	// The root endpoint usually serves an index page or load balancer probes.
+	"root": {
+		{Paths: []string{"/"}},
+	},
+}
+
+// statusCodePattern matches a response code or a range such as `4XX`.
+var statusCodePattern = regexp.MustCompile(`^[1-5](?:[0-9]{2}|XX)$`)
+
+// ignoreRule is a compiled config.IgnoreRule.
+type ignoreRule struct {
+	name         string
+	paths        []*regexp.Regexp
+	methods      []string
+	services     []string
+	statusCodes  []string
+	contentTypes []string
+}
+
+// newIgnoreRules compiles the configured presets and rules, in this order.
+func newIgnoreRules(cfg config.Ignore) ([]*ignoreRule, error) {
+	var rules []*ignoreRule
+	for _, preset := range cfg.Presets {
+		presetRules, exists := ignorePresets[preset]
+		if !exists {
+			return nil, fmt.Errorf("unknown ignore rule preset `%s`", preset)
+		}
+		for _, presetRule := range presetRules {
+			presetRule.Name = preset
+			rule, err := newIgnoreRule(presetRule)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+		}
+	}
+	for _, ruleCfg := range cfg.Rules {
+		rule, err := newIgnoreRule(ruleCfg)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func newIgnoreRule(cfg config.IgnoreRule) (*ignoreRule, error) {
+	rule := &ignoreRule{name: cfg.Name, services: cfg.Services}
+	for _, glob := range cfg.Paths {
+		rule.paths = append(rule.paths, globRegexp(glob))
+	}
+	for _, expr := range cfg.PathRegex {
+		pathRegex, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path regex `%s` for ignore rule `%s`: %w", expr, cfg.Name, err)
+		}
+		rule.paths = append(rule.paths, pathRegex)
+	}
+	for _, method := range cfg.Methods {
+		rule.methods = append(rule.methods, strings.ToUpper(method))
+	}
+	for _, statusCode := range cfg.StatusCodes {
+		statusCode = strings.ToUpper(statusCode)
+		if !statusCodePattern.MatchString(statusCode) {
+			return nil, fmt.Errorf("invalid status code `%s` for ignore rule `%s`", statusCode, cfg.Name)
+		}
+		rule.statusCodes = append(rule.statusCodes, statusCode)
+	}
+	for _, contentType := range cfg.ContentTypes {
+		rule.contentTypes = append(rule.contentTypes, strings.ToLower(contentType))
+	}
+	return rule, nil
+}
+
+// globRegexp converts a path glob into a regular expression, `*` matches
+// within a path segment and `**` across segments.
+func globRegexp(glob string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for idx := 0; idx < len(glob); idx++ {
+		switch glob[idx] {
+		case '*':
+			if idx+1 < len(glob) && glob[idx+1] == '*' {
+				expr.WriteString(".*")
+				idx++
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(glob[idx : idx+1]))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// matchesEvent reports whether the rule matches the event sent to requestPath.
+func (r *ignoreRule) matchesEvent(event apievent.ApiEvent, requestPath string) bool {
+	return r.matchesRequest(event.RequestMethod, requestPath) &&
+		matchesAny(r.services, event.ServiceName) &&
+		r.matchesStatusCode(event.ResponseCode) &&
+		r.matchesContentType(event.ContentType)
+}
+
+// matchesOperation reports whether the rule matches the spec operation served
+// at any of the paths, prefixed with their base paths like request paths are.
+// Only the rules without service, status code and content type conditions can,
+// as the operation doesn't determine them.
+func (r *ignoreRule) matchesOperation(operation *inventory.Operation, servedPaths []string) bool {
+	if len(r.services) > 0 || len(r.statusCodes) > 0 || len(r.contentTypes) > 0 {
+		return false
+	}
+	return slices.ContainsFunc(servedPaths, func(servedPath string) bool {
+		return r.matchesRequest(operation.Method, servedPath)
+	})
+}
+
+func (r *ignoreRule) matchesRequest(method, requestPath string) bool {
+	if len(r.methods) > 0 && !slices.Contains(r.methods, strings.ToUpper(method)) {
+		return false
+	}
+	if len(r.paths) == 0 {
+		return true
+	}
+	for _, pathRegex := range r.paths {
+		if pathRegex.MatchString(requestPath) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ignoreRule) matchesStatusCode(statusCode int) bool {
+	if len(r.statusCodes) == 0 {
+		return true
+	}
+	code := strconv.Itoa(statusCode)
+	for _, pattern := range r.statusCodes {
+		if pattern == code || (strings.HasSuffix(pattern, "XX") && len(code) == 3 && pattern[0] == code[0]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ignoreRule) matchesContentType(contentType string) bool {
+	if len(r.contentTypes) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, pattern := range r.contentTypes {
+		if pattern == mediaType || (strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*"))) {
+			return true
+		}
+	}
+	return false
+}
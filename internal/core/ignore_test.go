@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/config"
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+func TestGlobRegexp(t *testing.T) {
+	for glob, matches := range map[string]map[string]bool{
+		"/assets/**": {"/assets/app.js": true, "/assets/img/logo.png": true, "/assets": false, "/api/assets/x": false},
+		"/users/*":   {"/users/1": true, "/users/1/orders": false},
+		"/v?/health": {"/v1/health": true, "/v10/health": false},
+		"/a.b":       {"/a.b": true, "/aXb": false},
+	} {
+		for requestPath, want := range matches {
+			assert.Equal(t, want, globRegexp(glob).MatchString(requestPath), "%s %s", glob, requestPath)
+		}
+	}
+}
+
+func TestIgnoreRule_MatchesEvent(t *testing.T) {
+	rule, err := newIgnoreRule(config.IgnoreRule{
+		Name:         "internal",
+		Paths:        []string{"/internal/**"},
+		Methods:      []string{"get"},
+		Services:     []string{"*.monitoring"},
+		StatusCodes:  []string{"2xx", "404"},
+		ContentTypes: []string{"text/*"},
+	})
+	require.NoError(t, err)
+
+	event := apievent.ApiEvent{ServiceName: "prom.monitoring", RequestMethod: "GET", ResponseCode: 204, ContentType: "text/plain; charset=utf-8"}
+	assert.True(t, rule.matchesEvent(event, "/internal/stats"))
+	assert.False(t, rule.matchesEvent(event, "/api/stats"))
+
+	for _, mismatch := range []apievent.ApiEvent{
+		{ServiceName: "prom.monitoring", RequestMethod: "POST", ResponseCode: 204, ContentType: "text/plain"},
+		{ServiceName: "users", RequestMethod: "GET", ResponseCode: 204, ContentType: "text/plain"},
+		{ServiceName: "prom.monitoring", RequestMethod: "GET", ResponseCode: 500, ContentType: "text/plain"},
+		{ServiceName: "prom.monitoring", RequestMethod: "GET", ResponseCode: 404, ContentType: "application/json"},
+	} {
+		assert.False(t, rule.matchesEvent(mismatch, "/internal/stats"), "%+v", mismatch)
+	}
+	assert.True(t, rule.matchesEvent(apievent.ApiEvent{ServiceName: "prom.monitoring", RequestMethod: "GET", ResponseCode: 404, ContentType: "text/html"}, "/internal/stats"))
+}
+
+func TestIgnoreRule_MatchesOperation(t *testing.T) {
+	pathRule, err := newIgnoreRule(config.IgnoreRule{Name: "health", Paths: []string{"/health"}})
+	require.NoError(t, err)
+	statusRule, err := newIgnoreRule(config.IgnoreRule{Name: "not-found", Paths: []string{"/health"}, StatusCodes: []string{"404"}})
+	require.NoError(t, err)
+
+	operation := &inventory.Operation{Method: "GET", PathTemplate: "/health"}
+	assert.True(t, pathRule.matchesOperation(operation, []string{"/health"}))
+	assert.False(t, statusRule.matchesOperation(operation, []string{"/health"}))
+	assert.False(t, pathRule.matchesOperation(operation, []string{"/api/health"}))
+}
+
+func TestScan_IgnoredOrphansUnderBasePaths(t *testing.T) {
+	m := newTestManager()
+	var err error
+	m.ignoreRules, err = newIgnoreRules(config.Ignore{Rules: []config.IgnoreRule{{Name: "internal", Paths: []string{"/api/internal/**"}}}})
+	require.NoError(t, err)
+
+	inv := &inventory.Inventory{
+		PathItems: []*inventory.PathItem{
+			{
+				PathTemplate: "/internal/stats",
+				BasePaths:    []string{"/api"},
+				Operations:   []*inventory.Operation{{Method: "GET", PathTemplate: "/internal/stats"}},
+			},
+			{
+				PathTemplate: "/users",
+				BasePaths:    []string{"/api"},
+				Operations:   []*inventory.Operation{{Method: "GET", PathTemplate: "/users"}},
+			},
+		},
+	}
+
+	report := scanEvents(m, inv,
+		apievent.ApiEvent{RequestMethod: "GET", RequestPath: "/api/internal/stats"},
+		apievent.ApiEvent{RequestMethod: "GET", RequestPath: "/api/internal/debug"},
+	)
+	assert.Equal(t, []IgnoredTraffic{{Rule: "internal", Events: 2, Occurrences: 2}}, report.Ignored)
+	assert.Empty(t, report.ShadowAPIs)
+	assert.Equal(t, []API{{RequestMethod: "GET", RequestPath: "/api/users", SpecPath: "/users", Spec: "test"}}, report.OrphanAPIs)
+}
+
+func TestNewIgnoreRules(t *testing.T) {
+	rules, err := newIgnoreRules(config.Ignore{
+		Presets: []string{"cors-preflight"},
+		Rules:   []config.IgnoreRule{{Name: "debug", PathRegex: []string{"^/debug/"}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, "cors-preflight", rules[0].name)
+	assert.Equal(t, "debug", rules[1].name)
+
+	for name, cfg := range map[string]config.Ignore{
+		"unknown preset": {Presets: []string{"unknown"}},
+		"invalid regex":  {Rules: []config.IgnoreRule{{Name: "invalid", PathRegex: []string{"("}}}},
+		"invalid status": {Rules: []config.IgnoreRule{{Name: "invalid", StatusCodes: []string{"4X"}}}},
+	} {
+		_, err := newIgnoreRules(cfg)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestIgnorePresets_StaticAssets(t *testing.T) {
+	rules, err := newIgnoreRules(config.Ignore{Presets: config.DefaultIgnorePresets})
+	require.NoError(t, err)
+
+	ignored := func(event apievent.ApiEvent) bool {
+		for _, rule := range rules {
+			if rule.matchesEvent(event, event.RequestPath) {
+				return true
+			}
+		}
+		return false
+	}
+	assert.True(t, ignored(apievent.ApiEvent{RequestMethod: "GET", RequestPath: "/assets/app.css"}))
+	assert.True(t, ignored(apievent.ApiEvent{RequestMethod: "GET", RequestPath: "/logo.PNG"}))
+	assert.True(t, ignored(apievent.ApiEvent{RequestMethod: "GET", RequestPath: "/avatar/1", ContentType: "image/webp"}))
+	assert.True(t, ignored(apievent.ApiEvent{RequestMethod: "GET", RequestPath: "/healthz"}))
+	assert.True(t, ignored(apievent.ApiEvent{RequestMethod: "GET", RequestPath: "/"}))
+	assert.True(t, ignored(apievent.ApiEvent{RequestMethod: "POST", RequestPath: "/"}))
+	// Endpoints the previous hard-coded filter hid.
+	assert.False(t, ignored(apievent.ApiEvent{RequestMethod: "GET", RequestPath: "/api/v1/js"}))
+	assert.False(t, ignored(apievent.ApiEvent{RequestMethod: "GET", RequestPath: "/environment"}))
+	assert.False(t, ignored(apievent.ApiEvent{RequestMethod: "POST", RequestPath: "/healthz"}))
+}
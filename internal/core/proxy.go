@@ -0,0 +1,213 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"time"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/config"
+	"github.com/5gsec/api-speculator/internal/util"
+)
+
+const proxyShutdownTimeout = 10 * time.Second
+
+// RunProxy runs a reverse proxy in front of the configured upstream and
+// evaluates the proxied traffic against the configured specs as it flows.
+// Shadow and zombie API hits are logged live and the report of the whole
+// session is exported once ctx is canceled.
+func RunProxy(ctx context.Context, configFilePath string) error {
+	mgr := &Manager{
+		Ctx:    ctx,
+		Logger: util.GetLogger(),
+	}
+
+	mgr.Logger.Info("starting speculator proxy")
+
+	cfg, err := config.NewProxy(configFilePath, mgr.Logger)
+	if err != nil {
+		return err
+	}
+	mgr.Cfg = cfg
+	if mgr.ignoreRules, err = newIgnoreRules(mgr.Cfg.Ignore); err != nil {
+		return err
+	}
+
+	var report apiReport
+	specs, specErrs := mgr.buildSpecs()
+	report.Errors = append(report.Errors, specLoadErrors(specErrs)...)
+
+	upstream, err := url.Parse(mgr.Cfg.Proxy.Upstream)
+	if err != nil {
+		return fmt.Errorf("invalid proxy upstream URL: %w", err)
+	}
+	p := mgr.newProxy(specs, upstream)
+
+	servers := []*http.Server{{Addr: mgr.Cfg.Proxy.Listen, Handler: p}}
+	if mgr.Cfg.Proxy.AdminListen != "" {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/findings", p.serveFindings)
+		servers = append(servers, &http.Server{Addr: mgr.Cfg.Proxy.AdminListen, Handler: mux})
+	}
+
+	serveErr := make(chan error, len(servers))
+	for _, server := range servers {
+		listener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on `%s`: %w", server.Addr, err)
+		}
+		server.ReadHeaderTimeout = 30 * time.Second
+		go func(server *http.Server) {
+			if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- err
+			}
+		}(server)
+	}
+	mgr.Logger.Infof("proxying `%s` to `%s`", mgr.Cfg.Proxy.Listen, upstream)
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		runErr = fmt.Errorf("proxy failed: %w", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), proxyShutdownTimeout)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			mgr.Logger.Warnf("failed to stop proxy listening on `%s`: %v", server.Addr, err)
+		}
+	}
+	if runErr != nil {
+		return runErr
+	}
+
+	p.scan.report(&report)
+	mgr.applySuppressions(&report, time.Now())
+	if err := mgr.exportJsonReport(mgr.Cfg.Exporter.JsonReportFilePath, report); err != nil {
+		return err
+	}
+	mgr.Logger.Infof("successfully generated `%s` JSON report", mgr.Cfg.Exporter.JsonReportFilePath)
+
+	if len(specErrs) > 0 {
+		return fmt.Errorf("failed to load %d spec(s), see the `%s` JSON report", len(specErrs), mgr.Cfg.Exporter.JsonReportFilePath)
+	}
+	return nil
+}
+
+// proxy is a reverse proxy recording the proxied requests as events and
+// evaluating them against the specs.
+type proxy struct {
+	scan         *scan
+	serviceName  string
+	reverseProxy *httputil.ReverseProxy
+}
+
+// liveFindings are the shadow and zombie APIs hit since the proxy started.
+type liveFindings struct {
+	ShadowAPIs []API `json:"shadowApis"`
+	ZombieAPIs []API `json:"zombieApis"`
+}
+
+// proxyExchangeKey is the context key of the upstream request path.
+type proxyExchangeKey struct{}
+
+func (m *Manager) newProxy(specs []*apiSpec, upstream *url.URL) *proxy {
+	p := &proxy{
+		scan:        m.newScan(specs),
+		serviceName: upstream.Host,
+	}
+	p.scan.onFinding = func(kind string, api API) {
+		switch kind {
+		case findingKindShadow:
+			m.Logger.Warnf("shadow API hit: %s %s (%s)", api.RequestMethod, api.RequestPath, api.Category)
+		case findingKindZombie:
+			m.Logger.Warnf("zombie API hit: %s %s (%s)", api.RequestMethod, api.RequestPath, api.SpecPath)
+		}
+	}
+	p.reverseProxy = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(upstream)
+			r.SetXForwarded()
+			if requestPath, ok := r.In.Context().Value(proxyExchangeKey{}).(*string); ok {
+				*requestPath = r.Out.URL.RequestURI()
+			}
+		},
+	}
+	return p
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The events record the path requested to the upstream, e.g. including
+	// the upstream URL path.
+	requestPath := r.URL.RequestURI()
+	r = r.WithContext(context.WithValue(r.Context(), proxyExchangeKey{}, &requestPath))
+
+	recorder := &statusRecorder{ResponseWriter: w}
+	p.reverseProxy.ServeHTTP(recorder, r)
+
+	p.scan.add(apievent.ApiEvent{
+		ServiceName:   p.serviceName,
+		RequestMethod: r.Method,
+		RequestPath:   requestPath,
+		ResponseCode:  recorder.statusCode(),
+		ContentType:   recorder.Header().Get("Content-Type"),
+	})
+}
+
+func (p *proxy) serveFindings(w http.ResponseWriter, _ *http.Request) {
+	var findings liveFindings
+	findings.ShadowAPIs, findings.ZombieAPIs = p.scan.findings()
+	body, err := json.MarshalIndent(findings, "", " ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// statusRecorder records the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	// Informational responses precede the final one.
+	if r.status == 0 && statusCode >= http.StatusOK {
+		r.status = statusCode
+	}
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streamed responses.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *statusRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/5gsec/api-speculator/internal/config"
+)
+
+func TestProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/carts" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer upstream.Close()
+	upstreamURL, err := url.Parse(upstream.URL + "/api")
+	require.NoError(t, err)
+
+	m := newTestManager()
+	inv := newTestInventory()
+	spec := &apiSpec{cfg: config.Spec{Name: "test"}, inventory: inv, trie: m.buildTrie(inv, config.PathPrefixes{Add: []string{"/api"}})}
+	p := m.newProxy([]*apiSpec{spec}, upstreamURL)
+	front := httptest.NewServer(p)
+	defer front.Close()
+
+	for _, request := range []struct{ method, path string }{
+		{http.MethodGet, "/users/1"},
+		{http.MethodGet, "/orders/1"},
+		{http.MethodGet, "/orders/2"},
+		{http.MethodDelete, "/users/1"},
+		{http.MethodGet, "/carts"},
+		{http.MethodGet, "/carts"},
+	} {
+		req, err := http.NewRequest(request.method, front.URL+request.path, nil)
+		require.NoError(t, err)
+		response, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, response.Body.Close())
+	}
+
+	service := upstreamURL.Host
+	shadowApis, zombieApis := p.scan.findings()
+	assert.Equal(t, []API{
+		{ServiceName: service, RequestMethod: "DELETE", RequestPath: "/api/users/1", Occurrences: 1, Category: ShadowCategoryUndocumentedMethod, Spec: "test"},
+		{ServiceName: service, RequestMethod: "GET", RequestPath: "/api/carts", Occurrences: 2, Category: ShadowCategoryUnknownPath, Spec: "test"},
+	}, shadowApis)
+	// Zombie APIs are aggregated by spec operation.
+	assert.Equal(t, []API{
+		{ServiceName: service, RequestMethod: "GET", RequestPath: "/api/orders/1", SpecPath: "/orders/{id}", Occurrences: 2, Spec: "test"},
+	}, zombieApis)
+
+	var report apiReport
+	p.scan.report(&report)
+	assert.Equal(t, int64(6), report.Stats.Events)
+	assert.Equal(t, 3, report.Stats.AggregationKeys)
+}
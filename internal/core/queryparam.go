@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"maps"
+	"slices"
+	"unicode"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/apispec"
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+const (
+	// maxQueryParamSamples bounds the redacted sample values kept for each
+	// undocumented query parameter.
+	maxQueryParamSamples = 3
+
+	maxRedactedLength = 32
+)
+
+// addQueryParams compares the query parameters of the event with the ones the
+// operation documents. Undocumented ones are aggregated by API and name, the
+// documented ones are recorded as sent.
+func (s *scan) addQueryParams(spec *apiSpec, event apievent.ApiEvent, operation *inventory.Operation) {
+	_, values := apispec.ExtractQueryAndParams(event.RequestPath)
+	if len(values) == 0 {
+		return
+	}
+
+	// Sorted to keep the order of the findings deterministic.
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if documentsQueryParam(operation, name) {
+			sent := s.sentQueryParams[spec][operation]
+			if sent == nil {
+				sent = make(map[string]struct{})
+				s.sentQueryParams[spec][operation] = sent
+			}
+			sent[name] = struct{}{}
+			continue
+		}
+
+		key := operationApiKey(spec, event, operation) + " " + name
+		idx, exists := s.undocumentedQueryIndex[key]
+		if !exists {
+			if !s.reserveKey(event.Occurrences) {
+				continue
+			}
+			idx = len(s.undocumentedQueryParams)
+			s.undocumentedQueryIndex[key] = idx
+			s.undocumentedQueryParams = append(s.undocumentedQueryParams, QueryParam{
+				ClusterName:   event.ClusterName,
+				ServiceName:   event.ServiceName,
+				RequestMethod: operation.Method,
+				SpecPath:      operation.PathTemplate,
+				Spec:          spec.cfg.Name,
+				Name:          name,
+			})
+		}
+
+		param := &s.undocumentedQueryParams[idx]
+		param.Occurrences += event.Occurrences
+		for _, value := range values[name] {
+			sample := redactQueryValue(value)
+			if len(param.Samples) < maxQueryParamSamples && !slices.Contains(param.Samples, sample) {
+				param.Samples = append(param.Samples, sample)
+			}
+		}
+	}
+}
+
+func documentsQueryParam(operation *inventory.Operation, name string) bool {
+	for _, param := range operation.Params {
+		if param.In == "query" && param.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// findUnsentRequiredQueryParams returns the required query parameters of the
+// operations of the spec that received traffic, but never with the parameter.
+func findUnsentRequiredQueryParams(spec *apiSpec, exercisedOperations map[*inventory.Operation]struct{}, sentQueryParams map[*inventory.Operation]map[string]struct{}) []QueryParam {
+	var unsent []QueryParam
+	for _, operation := range spec.inventory.Operations() {
+		if _, exercised := exercisedOperations[operation]; !exercised {
+			continue
+		}
+		for _, param := range operation.Params {
+			if param.In != "query" || !param.Required {
+				continue
+			}
+			if _, sent := sentQueryParams[operation][param.Name]; !sent {
+				unsent = append(unsent, QueryParam{
+					RequestMethod: operation.Method,
+					SpecPath:      operation.PathTemplate,
+					Spec:          spec.cfg.Name,
+					Name:          param.Name,
+				})
+			}
+		}
+	}
+	return unsent
+}
+
+// redactQueryValue keeps the shape of a value only: letters are replaced with
+// `a` or `A`, digits with `9`, other characters are kept. Long values are
+// truncated.
+func redactQueryValue(value string) string {
+	redacted := make([]rune, 0, min(len(value), maxRedactedLength))
+	for _, r := range value {
+		if len(redacted) == maxRedactedLength {
+			return string(redacted) + "…"
+		}
+		switch {
+		case unicode.IsUpper(r):
+			r = 'A'
+		case unicode.IsLetter(r):
+			r = 'a'
+		case unicode.IsDigit(r):
+			r = '9'
+		}
+		redacted = append(redacted, r)
+	}
+	return string(redacted)
+}
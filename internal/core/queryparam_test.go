@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+func TestScan_QueryParams(t *testing.T) {
+	inv := &inventory.Inventory{
+		PathItems: []*inventory.PathItem{
+			{
+				PathTemplate: "/users",
+				Operations: []*inventory.Operation{
+					{Method: "GET", PathTemplate: "/users", Params: []inventory.Param{
+						{Name: "page", In: "query"},
+						{Name: "tenant", In: "query", Required: true},
+						{Name: "X-Tenant", In: "header", Required: true},
+					}},
+					{Method: "POST", PathTemplate: "/users", Params: []inventory.Param{
+						{Name: "dryRun", In: "query", Required: true},
+					}},
+				},
+			},
+			{
+				PathTemplate: "/orders",
+				Operations: []*inventory.Operation{
+					{Method: "GET", PathTemplate: "/orders", Params: []inventory.Param{
+						{Name: "status", In: "query", Required: true},
+					}},
+				},
+			},
+		},
+	}
+
+	report := scanEvents(newTestManager(), inv,
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users?page=2&email=John.Doe@example.com&debug=1", Occurrences: 2},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users?email=jane@example.org&email=jane@example.org"},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "POST", RequestPath: "/users?dryRun=true"},
+		// Shadow APIs have no documented parameters to compare with.
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts?debug=1"},
+	)
+
+	assert.Equal(t, []QueryParam{
+		{ServiceName: "svc", RequestMethod: "GET", SpecPath: "/users", Spec: "test", Name: "debug", Occurrences: 2, Samples: []string{"9"}},
+		{ServiceName: "svc", RequestMethod: "GET", SpecPath: "/users", Spec: "test", Name: "email", Occurrences: 3, Samples: []string{"Aaaa.Aaa@aaaaaaa.aaa", "aaaa@aaaaaaa.aaa"}},
+	}, report.UndocumentedQueryParams)
+	// Operations without traffic are orphan APIs instead.
+	assert.Equal(t, []QueryParam{
+		{RequestMethod: "GET", SpecPath: "/users", Spec: "test", Name: "tenant"},
+	}, report.UnsentRequiredQueryParams)
+}
+
+func TestRedactQueryValue(t *testing.T) {
+	assert.Equal(t, "Aaaa-99", redactQueryValue("John-42"))
+	assert.Equal(t, "", redactQueryValue(""))
+	assert.Equal(t, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa…", redactQueryValue("abcdefghijklmnopqrstuvwxyzabcdefghij"))
+}
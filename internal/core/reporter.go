@@ -6,6 +6,20 @@ package core
 import (
 	"encoding/json"
 	"os"
+	"time"
+)
+
+// Shadow API sub-categories.
+const (
+	// ShadowCategoryUnknownPath is used when the request path is not documented at all.
+	ShadowCategoryUnknownPath = "unknown-path"
+	// ShadowCategoryUndocumentedMethod is used when the request path is documented
+	// but the request method is not.
+	ShadowCategoryUndocumentedMethod = "undocumented-method"
+	// ShadowCategoryParameterMismatch is used when the request path only matches
+	// documented paths whose path parameter schemas reject its values, e.g.
+	// `/users/export` for `/users/{id}` with an integer id.
+	ShadowCategoryParameterMismatch = "parameter-type-mismatch"
 )
 
 type API struct {
@@ -13,24 +27,163 @@ type API struct {
 	ServiceName   string `json:"serviceName,omitempty"`
 	RequestMethod string `json:"requestMethod"`
 	RequestPath   string `json:"requestPath"`
+	SpecPath      string `json:"specPath,omitempty"`
 	Occurrences   int    `json:"occurrences,omitempty"`
+	Category      string `json:"category,omitempty"`
+	Spec          string `json:"spec,omitempty"`
+	// Detail explains the finding, e.g. the parameter value a spec path
+	// rejects.
+	Detail string `json:"detail,omitempty"`
+	// FirstSeen and LastSeen bound the times the API was hit at, if the
+	// traffic source records them.
+	FirstSeen time.Time `json:"firstSeen,omitzero"`
+	LastSeen  time.Time `json:"lastSeen,omitzero"`
+}
+
+// QueryParam is a query parameter of a spec operation, either observed but not
+// documented, or documented as required but never observed.
+type QueryParam struct {
+	ClusterName   string `json:"clusterName,omitempty"`
+	ServiceName   string `json:"serviceName,omitempty"`
+	RequestMethod string `json:"requestMethod"`
+	SpecPath      string `json:"specPath"`
+	Spec          string `json:"spec,omitempty"`
+	Name          string `json:"name"`
+	Occurrences   int    `json:"occurrences,omitempty"`
+	// Samples holds redacted sample values, keeping only their shape.
+	Samples []string `json:"samples,omitempty"`
+}
+
+// UndocumentedStatusCode is a response status code observed for a spec
+// operation that doesn't document it.
+type UndocumentedStatusCode struct {
+	ClusterName   string `json:"clusterName,omitempty"`
+	ServiceName   string `json:"serviceName,omitempty"`
+	RequestMethod string `json:"requestMethod"`
+	SpecPath      string `json:"specPath"`
+	Spec          string `json:"spec,omitempty"`
+	StatusCode    int    `json:"statusCode"`
+	Occurrences   int    `json:"occurrences,omitempty"`
+}
+
+// UnmappedService is a service whose traffic isn't documented by any configured
+// spec.
+type UnmappedService struct {
+	ClusterName string `json:"clusterName,omitempty"`
+	ServiceName string `json:"serviceName,omitempty"`
+	Occurrences int    `json:"occurrences,omitempty"`
+}
+
+// SuppressedFinding is a finding a suppression keeps out of the findings.
+type SuppressedFinding struct {
+	API
+	FindingType string    `json:"findingType"`
+	Owner       string    `json:"owner"`
+	Reason      string    `json:"reason"`
+	Expires     time.Time `json:"expires,omitzero"`
+}
+
+// IgnoredTraffic counts the events an ignore rule, or the rules of a preset,
+// excluded from the scan.
+type IgnoredTraffic struct {
+	Rule        string `json:"rule"`
+	Events      int64  `json:"events"`
+	Occurrences int64  `json:"occurrences"`
+}
+
+// SkippedTraffic counts the events routed to a spec that failed to load, they
+// are not evaluated.
+type SkippedTraffic struct {
+	Spec        string `json:"spec"`
+	Events      int64  `json:"events"`
+	Occurrences int64  `json:"occurrences"`
+}
+
+// Scan error types.
+const (
+	// ScanErrorSpecLoadFailed is used when a spec, or one of its files, could not
+	// be loaded.
+	ScanErrorSpecLoadFailed = "spec-load-failed"
+)
+
+// ScanError is a failure that makes the report incomplete.
+type ScanError struct {
+	Type    string `json:"type"`
+	Spec    string `json:"spec,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Message string `json:"message"`
+}
+
+// Scan warning types.
+const (
+	// ScanWarningSuppressionExpired is used when a suppression expired, its
+	// findings are reported again.
+	ScanWarningSuppressionExpired = "suppression-expired"
+)
+
+// ScanWarning is an issue that doesn't make the report incomplete but needs
+// attention.
+type ScanWarning struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// ScanStats describes the work and the resources of a scan.
+type ScanStats struct {
+	Events      int64 `json:"events"`
+	Occurrences int64 `json:"occurrences"`
+
+	// AggregationKeys is the number of aggregated APIs, undocumented query
+	// parameters and status codes, and unmapped services.
+	AggregationKeys int `json:"aggregationKeys"`
+
+	// DroppedOccurrences counts the occurrences of the APIs and services that
+	// exceeded the maximum number of aggregation keys.
+	DroppedOccurrences int64 `json:"droppedOccurrences,omitempty"`
+
+	// ExcludedShadowApis counts the shadow APIs excluded because they were only
+	// ever answered with 404 or 405.
+	ExcludedShadowApis int `json:"excludedShadowApis,omitempty"`
+
+	// HeapAllocBytes is the heap memory in use at the end of the scan.
+	HeapAllocBytes uint64 `json:"heapAllocBytes"`
+
+	// SysBytes is the memory obtained from the OS, an upper bound of the peak
+	// memory usage.
+	SysBytes uint64 `json:"sysBytes"`
+}
+
+// ScanWindow is the time window the scanned traffic was restricted to, an unset
+// side is unbounded.
+type ScanWindow struct {
+	Since time.Time `json:"since,omitzero"`
+	Until time.Time `json:"until,omitzero"`
 }
 
 type apiReport struct {
-	TenantId   int    `json:"tenantId"`
-	ScanName   string `json:"scan_name"`
-	ShadowAPIs []API  `json:"shadowApis,omitempty"`
-	ZombieAPIs []API  `json:"zombieApis,omitempty"`
-	OrphanAPIs []API  `json:"orphanApis,omitempty"`
-}
-
-func (m *Manager) exportJsonReport(reportFilePath string, shadowApis, zombieApis, orphanApis []API) error {
-	report := apiReport{
-		TenantId:   m.Cfg.Environment.TenantId,
-		ScanName:   m.Cfg.ScanName,
-		ShadowAPIs: shadowApis,
-		ZombieAPIs: zombieApis,
-		OrphanAPIs: orphanApis,
+	TenantId                  int                      `json:"tenantId"`
+	ScanName                  string                   `json:"scan_name"`
+	Window                    *ScanWindow              `json:"window,omitempty"`
+	ShadowAPIs                []API                    `json:"shadowApis,omitempty"`
+	ZombieAPIs                []API                    `json:"zombieApis,omitempty"`
+	OrphanAPIs                []API                    `json:"orphanApis,omitempty"`
+	UnmappedServices          []UnmappedService        `json:"unmappedServices,omitempty"`
+	UndocumentedQueryParams   []QueryParam             `json:"undocumentedQueryParams,omitempty"`
+	UnsentRequiredQueryParams []QueryParam             `json:"unsentRequiredQueryParams,omitempty"`
+	UndocumentedStatusCodes   []UndocumentedStatusCode `json:"undocumentedStatusCodes,omitempty"`
+	Suppressed                []SuppressedFinding      `json:"suppressed,omitempty"`
+	Ignored                   []IgnoredTraffic         `json:"ignored,omitempty"`
+	Skipped                   []SkippedTraffic         `json:"skipped,omitempty"`
+	Errors                    []ScanError              `json:"errors,omitempty"`
+	Warnings                  []ScanWarning            `json:"warnings,omitempty"`
+	Stats                     ScanStats                `json:"stats"`
+}
+
+func (m *Manager) exportJsonReport(reportFilePath string, report apiReport) error {
+	report.TenantId = m.Cfg.Environment.TenantId
+	report.ScanName = m.Cfg.ScanName
+	if window := m.Cfg.Traffic.Window; window.IsSet() {
+		report.Window = &ScanWindow{Since: window.Since, Until: window.Until}
 	}
 
 	f, err := os.OpenFile(reportFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o666)
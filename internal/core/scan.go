@@ -0,0 +1,349 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/apispec"
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+// scan evaluates the events against the specs as they are streamed. Only
+// aggregates are kept in memory: shadow APIs and unmapped services are keyed by
+// service, method and normalized path, zombie and exercised operations by spec
+// operation, undocumented query parameters and status codes by service, spec
+// operation and name or code. The number of aggregated APIs, query parameters,
+// status codes and unmapped services is bounded, the occurrences beyond the
+// bound are counted as dropped.
+type scan struct {
+	mgr         *Manager
+	specs       []*apiSpec
+	maxKeys     int
+	ignoreRules []*ignoreRule
+
+	// onFinding, if set, is called the first time a shadow or zombie API is hit.
+	onFinding func(kind string, api API)
+
+	mu               sync.Mutex
+	shadowApis       apiAggregate
+	zombieApis       apiAggregate
+	unmappedServices []UnmappedService
+	unmappedIndex    map[UnmappedService]int
+	// exercisedOperations holds the spec operations that received traffic, by
+	// spec.
+	exercisedOperations map[*apiSpec]map[*inventory.Operation]struct{}
+	// sentQueryParams holds the names of the documented query parameters sent
+	// to the spec operations, by spec and operation.
+	sentQueryParams         map[*apiSpec]map[*inventory.Operation]map[string]struct{}
+	undocumentedQueryParams []QueryParam
+	undocumentedQueryIndex  map[string]int
+	undocumentedStatusCodes []UndocumentedStatusCode
+	undocumentedStatusIndex map[string]int
+	// handledShadowApis holds the indexes of the shadow APIs hit at least once
+	// with a status code other than 404 and 405.
+	handledShadowApis map[int]struct{}
+	// ignored counts the ignored events by rule name, in the order the rules
+	// first matched.
+	ignored      []IgnoredTraffic
+	ignoredIndex map[string]int
+	// skipped counts the events routed to specs that failed to load, by spec.
+	skipped      []SkippedTraffic
+	skippedIndex map[string]int
+	stats        ScanStats
+}
+
+// Finding kinds passed to onFinding.
+const (
+	findingKindShadow = "shadow"
+	findingKindZombie = "zombie"
+)
+
+// apiAggregate sums the occurrences of the APIs sharing a key, keeping the
+// order they were first found in.
+type apiAggregate struct {
+	indexByKey map[string]int
+	apis       []API
+}
+
+func (m *Manager) newScan(specs []*apiSpec) *scan {
+	s := &scan{
+		mgr:                     m,
+		specs:                   specs,
+		maxKeys:                 m.Cfg.Processing.MaxAggregationKeys,
+		ignoreRules:             m.ignoreRules,
+		ignoredIndex:            make(map[string]int),
+		skippedIndex:            make(map[string]int),
+		shadowApis:              apiAggregate{indexByKey: make(map[string]int)},
+		zombieApis:              apiAggregate{indexByKey: make(map[string]int)},
+		unmappedIndex:           make(map[UnmappedService]int),
+		exercisedOperations:     make(map[*apiSpec]map[*inventory.Operation]struct{}, len(specs)),
+		sentQueryParams:         make(map[*apiSpec]map[*inventory.Operation]map[string]struct{}, len(specs)),
+		undocumentedQueryIndex:  make(map[string]int),
+		undocumentedStatusIndex: make(map[string]int),
+		handledShadowApis:       make(map[int]struct{}),
+	}
+	for _, spec := range specs {
+		s.exercisedOperations[spec] = make(map[*inventory.Operation]struct{})
+		s.sentQueryParams[spec] = make(map[*inventory.Operation]map[string]struct{})
+	}
+	return s
+}
+
+// add evaluates the event against the first spec that matches it, unless an
+// ignore rule matches it or the spec failed to load. Events without occurrences
+// count once. It is safe for concurrent use.
+func (s *scan) add(event apievent.ApiEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event.Occurrences == 0 {
+		event.Occurrences = 1
+	}
+	s.stats.Events++
+	s.stats.Occurrences += int64(event.Occurrences)
+
+	if rule := s.ignoringRule(event); rule != nil {
+		s.addIgnored(rule.name, event.Occurrences)
+		return
+	}
+
+	spec := routeEvent(s.specs, event)
+	if spec == nil {
+		s.addUnmappedService(event)
+		return
+	}
+	if !spec.loaded() {
+		s.addSkipped(spec.cfg.Name, event.Occurrences)
+		return
+	}
+
+	shadowApi, zombieApi, operation := evaluateEvent(spec.trie, event)
+	if operation != nil {
+		s.exercisedOperations[spec][operation] = struct{}{}
+		s.addQueryParams(spec, event, operation)
+		s.addStatusCode(spec, event, operation)
+	}
+	if shadowApi != nil {
+		shadowApi.Spec = spec.cfg.Name
+		normalizedPath := apispec.UnifyParameterizedPathIfApplicable(shadowApi.RequestPath, false)
+		key := apiKey(spec, shadowApi, normalizedPath) + " " + shadowApi.Category
+		if idx, added := s.addApi(findingKindShadow, &s.shadowApis, key, *shadowApi); added && !isNotFoundStatus(event.ResponseCode) {
+			s.handledShadowApis[idx] = struct{}{}
+		}
+	}
+	if zombieApi != nil {
+		zombieApi.Spec = spec.cfg.Name
+		s.addApi(findingKindZombie, &s.zombieApis, operationApiKey(spec, event, operation), *zombieApi)
+	}
+}
+
+// ignoringRule returns the first ignore rule matching the event, nil if none
+// does.
+func (s *scan) ignoringRule(event apievent.ApiEvent) *ignoreRule {
+	requestPath, _ := apispec.GetPathAndQuery(event.RequestPath)
+	for _, rule := range s.ignoreRules {
+		if rule.matchesEvent(event, requestPath) {
+			return rule
+		}
+	}
+	return nil
+}
+
+func (s *scan) addIgnored(rule string, occurrences int) {
+	idx, exists := s.ignoredIndex[rule]
+	if !exists {
+		idx = len(s.ignored)
+		s.ignoredIndex[rule] = idx
+		s.ignored = append(s.ignored, IgnoredTraffic{Rule: rule})
+	}
+	s.ignored[idx].Events++
+	s.ignored[idx].Occurrences += int64(occurrences)
+}
+
+func (s *scan) addSkipped(spec string, occurrences int) {
+	idx, exists := s.skippedIndex[spec]
+	if !exists {
+		idx = len(s.skipped)
+		s.skippedIndex[spec] = idx
+		s.skipped = append(s.skipped, SkippedTraffic{Spec: spec})
+	}
+	s.skipped[idx].Events++
+	s.skipped[idx].Occurrences += int64(occurrences)
+}
+
+// apiKey identifies the APIs of a service hit with the same method on the same
+// path.
+func apiKey(spec *apiSpec, api *API, path string) string {
+	return fmt.Sprintf("%s %s %s %s", spec.cfg.Name, api.ClusterName, api.ServiceName, operationKey(api.RequestMethod, path))
+}
+
+// operationApiKey identifies the traffic of a service to a spec operation.
+// Operations documented on the same path template under other base paths are
+// distinct.
+func operationApiKey(spec *apiSpec, event apievent.ApiEvent, operation *inventory.Operation) string {
+	return fmt.Sprintf("%s %s %s %p", spec.cfg.Name, event.ClusterName, event.ServiceName, operation)
+}
+
+// addApi sums the occurrences of the API with the ones sharing its key and
+// widens their first and last seen times. It returns the index of the
+// aggregated API, false if it was dropped.
+func (s *scan) addApi(kind string, aggregate *apiAggregate, key string, api API) (int, bool) {
+	if idx, exists := aggregate.indexByKey[key]; exists {
+		aggregated := &aggregate.apis[idx]
+		aggregated.Occurrences += api.Occurrences
+		apievent.MergeSeen(&aggregated.FirstSeen, &aggregated.LastSeen, api.FirstSeen, api.LastSeen)
+		return idx, true
+	}
+	if !s.reserveKey(api.Occurrences) {
+		return 0, false
+	}
+
+	idx := len(aggregate.apis)
+	aggregate.indexByKey[key] = idx
+	aggregate.apis = append(aggregate.apis, api)
+	if s.onFinding != nil {
+		s.onFinding(kind, api)
+	}
+	return idx, true
+}
+
+func (s *scan) addUnmappedService(event apievent.ApiEvent) {
+	key := UnmappedService{ClusterName: event.ClusterName, ServiceName: event.ServiceName}
+	idx, exists := s.unmappedIndex[key]
+	if !exists {
+		if !s.reserveKey(event.Occurrences) {
+			return
+		}
+		idx = len(s.unmappedServices)
+		s.unmappedIndex[key] = idx
+		s.unmappedServices = append(s.unmappedServices, key)
+	}
+	s.unmappedServices[idx].Occurrences += event.Occurrences
+}
+
+// reserveKey reports whether a new aggregation key fits in the bound, counting
+// the occurrences as dropped otherwise.
+func (s *scan) reserveKey(occurrences int) bool {
+	if s.maxKeys > 0 && s.stats.AggregationKeys >= s.maxKeys {
+		if s.stats.DroppedOccurrences == 0 {
+			s.mgr.Logger.Warnf("reached the maximum of %d aggregated APIs and services, new ones are dropped", s.maxKeys)
+		}
+		s.stats.DroppedOccurrences += int64(occurrences)
+		return false
+	}
+	s.stats.AggregationKeys++
+	return true
+}
+
+// findings returns a copy of the shadow and zombie APIs hit so far.
+func (s *scan) findings() ([]API, []API) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.shadowFindings(), append([]API(nil), s.zombieApis.apis...)
+}
+
+// shadowFindings returns a copy of the shadow APIs hit so far, excluding the
+// ones only ever answered with 404 or 405 if configured so.
+func (s *scan) shadowFindings() []API {
+	if !s.mgr.Cfg.Detection.ExcludeNotFoundShadowApis {
+		return append([]API(nil), s.shadowApis.apis...)
+	}
+
+	var shadowApis []API
+	for idx, api := range s.shadowApis.apis {
+		if _, handled := s.handledShadowApis[idx]; handled {
+			shadowApis = append(shadowApis, api)
+		}
+	}
+	return shadowApis
+}
+
+// report adds the findings of the scan and its statistics to the report. Orphan
+// APIs are only reported if events were scanned.
+func (s *scan) report(report *apiReport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stats.Events > 0 {
+		shadowApis := s.shadowFindings()
+		s.stats.ExcludedShadowApis = len(s.shadowApis.apis) - len(shadowApis)
+		report.ShadowAPIs = append(report.ShadowAPIs, shadowApis...)
+		report.ZombieAPIs = append(report.ZombieAPIs, s.zombieApis.apis...)
+		for _, spec := range s.specs {
+			if !spec.loaded() {
+				continue
+			}
+			report.OrphanAPIs = append(report.OrphanAPIs, findOrphanApi(spec, s.exercisedOperations[spec], s.ignoreRules)...)
+			report.UnsentRequiredQueryParams = append(report.UnsentRequiredQueryParams,
+				findUnsentRequiredQueryParams(spec, s.exercisedOperations[spec], s.sentQueryParams[spec])...)
+		}
+		report.UndocumentedQueryParams = append(report.UndocumentedQueryParams, s.undocumentedQueryParams...)
+		report.UndocumentedStatusCodes = append(report.UndocumentedStatusCodes, s.undocumentedStatusCodes...)
+		report.UnmappedServices = append(report.UnmappedServices, s.unmappedServices...)
+		report.Ignored = append(report.Ignored, s.ignored...)
+		report.Skipped = append(report.Skipped, s.skipped...)
+	}
+
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	s.stats.HeapAllocBytes = memStats.HeapAlloc
+	s.stats.SysBytes = memStats.Sys
+	report.Stats = s.stats
+
+	s.mgr.Logger.Infof("scanned %d events (%d occurrences) into %d aggregated APIs, %d occurrences dropped, heap: %s, memory obtained from the OS: %s",
+		s.stats.Events, s.stats.Occurrences, s.stats.AggregationKeys, s.stats.DroppedOccurrences,
+		formatBytes(s.stats.HeapAllocBytes), formatBytes(s.stats.SysBytes))
+	if s.stats.ExcludedShadowApis > 0 {
+		s.mgr.Logger.Infof("excluded %d shadow APIs only ever answered with 404 or 405", s.stats.ExcludedShadowApis)
+	}
+}
+
+// findOrphanApi returns the operations of the spec that didn't receive traffic,
+// except the ones ignored by the rules. Orphan APIs are reported with the path
+// they are primarily served at, and the path template if it differs.
+func findOrphanApi(spec *apiSpec, exercisedOperations map[*inventory.Operation]struct{}, ignoreRules []*ignoreRule) []API {
+	var orphanApis []API
+	for _, pathItem := range spec.inventory.PathItems {
+		paths := servedPaths(pathItem, spec.cfg.PathPrefixes)
+		for _, operation := range pathItem.Operations {
+			ignored := slices.ContainsFunc(ignoreRules, func(rule *ignoreRule) bool {
+				return rule.matchesOperation(operation, paths)
+			})
+			if ignored {
+				continue
+			}
+			if _, exists := exercisedOperations[operation]; !exists {
+				orphanApi := API{
+					RequestMethod: operation.Method,
+					RequestPath:   paths[0],
+					Spec:          spec.cfg.Name,
+				}
+				if orphanApi.RequestPath != operation.PathTemplate {
+					orphanApi.SpecPath = operation.PathTemplate
+				}
+				orphanApis = append(orphanApis, orphanApi)
+			}
+		}
+	}
+	return orphanApis
+}
+
+func formatBytes(bytes uint64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := uint64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
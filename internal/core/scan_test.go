@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/config"
+)
+
+func TestScan_AggregatesParameterizedPaths(t *testing.T) {
+	report := scanEvents(newTestManager(), newTestInventory(),
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts/1", Occurrences: 2},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts/2", Occurrences: 3},
+		apievent.ApiEvent{ServiceName: "other", RequestMethod: "GET", RequestPath: "/carts/3"},
+	)
+	require.Len(t, report.ShadowAPIs, 2)
+	assert.Equal(t, "/carts/1", report.ShadowAPIs[0].RequestPath)
+	assert.Equal(t, 5, report.ShadowAPIs[0].Occurrences)
+	assert.Equal(t, "other", report.ShadowAPIs[1].ServiceName)
+	assert.Equal(t, int64(3), report.Stats.Events)
+	assert.Equal(t, int64(6), report.Stats.Occurrences)
+}
+
+func TestScan_MaxAggregationKeys(t *testing.T) {
+	m := newTestManager()
+	m.Cfg.Processing.MaxAggregationKeys = 2
+	report := scanEvents(m, newTestInventory(),
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts"},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/orders/1"},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/wishlists", Occurrences: 4},
+		// Known keys are still counted once the bound is reached.
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts"},
+	)
+	require.Len(t, report.ShadowAPIs, 1)
+	assert.Equal(t, 2, report.ShadowAPIs[0].Occurrences)
+	assert.Len(t, report.ZombieAPIs, 1)
+	assert.Equal(t, ScanStats{Events: 4, Occurrences: 7, AggregationKeys: 2, DroppedOccurrences: 4}, withoutMemStats(report.Stats))
+}
+
+func TestScan_NoEvents(t *testing.T) {
+	m := newTestManager()
+	inv := newTestInventory()
+	scan := m.newScan([]*apiSpec{{cfg: config.Spec{Name: "test"}, inventory: inv, trie: m.buildTrie(inv, config.PathPrefixes{})}})
+
+	var report apiReport
+	scan.report(&report)
+	assert.Empty(t, report.OrphanAPIs)
+	assert.Zero(t, report.Stats.Events)
+}
+
+func withoutMemStats(stats ScanStats) ScanStats {
+	stats.HeapAllocBytes, stats.SysBytes = 0, 0
+	return stats
+}
+
+func TestScan_IgnoreRules(t *testing.T) {
+	m := newTestManager()
+	var err error
+	m.ignoreRules, err = newIgnoreRules(config.Ignore{
+		Presets: []string{"static-assets"},
+		Rules: []config.IgnoreRule{
+			{Name: "users", Paths: []string{"/users/*"}},
+			{Name: "not-found", StatusCodes: []string{"404"}},
+		},
+	})
+	require.NoError(t, err)
+
+	report := scanEvents(m, newTestInventory(),
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/42", Occurrences: 2},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "DELETE", RequestPath: "/users/42"},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/app.js"},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts", ResponseCode: 404},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts", ResponseCode: 200},
+	)
+	require.Len(t, report.ShadowAPIs, 1)
+	assert.Equal(t, "/carts", report.ShadowAPIs[0].RequestPath)
+	assert.Equal(t, 1, report.ShadowAPIs[0].Occurrences)
+	assert.Equal(t, []IgnoredTraffic{
+		{Rule: "users", Events: 2, Occurrences: 3},
+		{Rule: "static-assets", Events: 1, Occurrences: 1},
+		{Rule: "not-found", Events: 1, Occurrences: 1},
+	}, report.Ignored)
+	// The ignored documented operation isn't an orphan API either.
+	assert.Equal(t, []API{{RequestMethod: "GET", RequestPath: "/orders/{id}", Spec: "test"}, {RequestMethod: "POST", RequestPath: "/orders/{id}", Spec: "test"}}, report.OrphanAPIs)
+}
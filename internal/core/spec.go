@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"errors"
+	"path"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/config"
+	"github.com/5gsec/api-speculator/internal/inventory"
+	"github.com/5gsec/api-speculator/internal/pathtrie"
+)
+
+// apiSpec is a loaded API specification along with the services it documents.
+type apiSpec struct {
+	cfg       config.Spec
+	inventory *inventory.Inventory
+	trie      pathtrie.PathTrie
+}
+
+// loaded reports whether the spec could be loaded. The events routed to a spec
+// that failed to load are skipped.
+func (s *apiSpec) loaded() bool {
+	return s.inventory != nil
+}
+
+// matches reports whether the event belongs to a service documented by the spec.
+func (s *apiSpec) matches(event apievent.ApiEvent) bool {
+	return matchesAny(s.cfg.ServiceNames, event.ServiceName) &&
+		matchesAny(s.cfg.ClusterNames, event.ClusterName)
+}
+
+// matchesAny reports whether value matches any of the glob patterns, an empty
+// list of patterns matches every value.
+func matchesAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, value); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSpecs loads every configured spec. Specs, or files of them, that fail
+// to load are returned as SpecLoadError alongside the ones that did load. Specs
+// that failed to load are kept in the routing order, so that the events of
+// their services aren't evaluated against another spec.
+func (m *Manager) buildSpecs() ([]*apiSpec, []*SpecLoadError) {
+	var specs []*apiSpec
+	var specErrs []*SpecLoadError
+	for _, specCfg := range m.Cfg.Specs {
+		inv, errs := m.buildModel(specCfg)
+		for _, err := range errs {
+			var specErr *SpecLoadError
+			if !errors.As(err, &specErr) {
+				specErr = &SpecLoadError{Source: specCfg.Path, Err: err}
+			}
+			specErr.Spec = specCfg.Name
+			m.Logger.Error(specErr)
+			specErrs = append(specErrs, specErr)
+		}
+		if inv == nil {
+			specs = append(specs, &apiSpec{cfg: specCfg})
+			continue
+		}
+		specs = append(specs, &apiSpec{
+			cfg:       specCfg,
+			inventory: inv,
+			trie:      m.buildTrie(inv, specCfg.PathPrefixes),
+		})
+	}
+	return specs, specErrs
+}
+
+// routeEvent returns the first spec that matches the event, nil if none does.
+func routeEvent(specs []*apiSpec, event apievent.ApiEvent) *apiSpec {
+	for _, spec := range specs {
+		if spec.matches(event) {
+			return spec
+		}
+	}
+	return nil
+}
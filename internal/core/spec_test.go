@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/config"
+)
+
+func TestRouteEvent(t *testing.T) {
+	users := &apiSpec{cfg: config.Spec{Name: "users", ServiceNames: []string{"users.*"}}}
+	orders := &apiSpec{cfg: config.Spec{Name: "orders", ServiceNames: []string{"orders"}, ClusterNames: []string{"prod-*"}}}
+	specs := []*apiSpec{users, orders}
+
+	assert.Equal(t, users, routeEvent(specs, apievent.ApiEvent{ClusterName: "dev", ServiceName: "users.default"}))
+	assert.Equal(t, orders, routeEvent(specs, apievent.ApiEvent{ClusterName: "prod-eu", ServiceName: "orders"}))
+	assert.Nil(t, routeEvent(specs, apievent.ApiEvent{ClusterName: "dev", ServiceName: "orders"}))
+}
+
+func TestScan_UnmappedServices(t *testing.T) {
+	m := newTestManager()
+	inv := newTestInventory()
+	orders := &apiSpec{
+		cfg:       config.Spec{Name: "orders", ServiceNames: []string{"orders"}, ClusterNames: []string{"prod-*"}},
+		inventory: inv,
+		trie:      m.buildTrie(inv, config.PathPrefixes{}),
+	}
+
+	scan := m.newScan([]*apiSpec{orders})
+	scan.add(apievent.ApiEvent{ClusterName: "prod-eu", ServiceName: "orders", RequestMethod: "POST", RequestPath: "/orders/1", Occurrences: 2})
+	scan.add(apievent.ApiEvent{ClusterName: "dev", ServiceName: "orders", RequestMethod: "GET", RequestPath: "/orders", Occurrences: 3})
+	scan.add(apievent.ApiEvent{ClusterName: "dev", ServiceName: "orders", RequestMethod: "POST", RequestPath: "/orders", Occurrences: 4})
+
+	var report apiReport
+	scan.report(&report)
+	assert.Equal(t, []UnmappedService{{ClusterName: "dev", ServiceName: "orders", Occurrences: 7}}, report.UnmappedServices)
+	assert.Equal(t, []API{{RequestMethod: "GET", RequestPath: "/users/{id}", Spec: "orders"}, {RequestMethod: "GET", RequestPath: "/orders/{id}", Spec: "orders"}}, report.OrphanAPIs)
+}
+
+func TestScan_FailedSpec(t *testing.T) {
+	m := newTestManager()
+	m.Cfg.Specs = []config.Spec{
+		{Name: "users", Path: filepath.Join(t.TempDir(), "missing.yaml"), ServiceNames: []string{"users"}},
+	}
+	specs, specErrs := m.buildSpecs()
+	require.Len(t, specErrs, 1)
+	require.Len(t, specs, 1)
+	assert.False(t, specs[0].loaded())
+
+	inv := newTestInventory()
+	catchAll := &apiSpec{cfg: config.Spec{Name: "catch-all"}, inventory: inv, trie: m.buildTrie(inv, config.PathPrefixes{})}
+	scan := m.newScan(append(specs, catchAll))
+	scan.add(apievent.ApiEvent{ServiceName: "users", RequestMethod: "GET", RequestPath: "/users/1/avatar", Occurrences: 2})
+	scan.add(apievent.ApiEvent{ServiceName: "users", RequestMethod: "GET", RequestPath: "/users/2/avatar"})
+	scan.add(apievent.ApiEvent{ServiceName: "orders", RequestMethod: "GET", RequestPath: "/carts"})
+
+	var report apiReport
+	scan.report(&report)
+	assert.Equal(t, []SkippedTraffic{{Spec: "users", Events: 2, Occurrences: 3}}, report.Skipped)
+	require.Len(t, report.ShadowAPIs, 1)
+	assert.Equal(t, "/carts", report.ShadowAPIs[0].RequestPath)
+	assert.Empty(t, report.UnmappedServices)
+	for _, api := range report.OrphanAPIs {
+		assert.Equal(t, "catch-all", api.Spec)
+	}
+}
+
+func TestMatchesAny(t *testing.T) {
+	assert.True(t, matchesAny(nil, "anything"))
+	assert.True(t, matchesAny([]string{"foo", "bar.*"}, "bar.svc"))
+	assert.False(t, matchesAny([]string{"foo"}, "bar"))
+}
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+// addStatusCode compares the response status code of the event with the ones
+// the operation documents, aggregating undocumented ones by API and status
+// code. Unknown status codes, and operations that don't document any, are not
+// compared.
+func (s *scan) addStatusCode(spec *apiSpec, event apievent.ApiEvent, operation *inventory.Operation) {
+	if event.ResponseCode == 0 || len(operation.ResponseCodes) == 0 || documentsStatusCode(operation.ResponseCodes, event.ResponseCode) {
+		return
+	}
+
+	key := fmt.Sprintf("%s %d", operationApiKey(spec, event, operation), event.ResponseCode)
+	idx, exists := s.undocumentedStatusIndex[key]
+	if !exists {
+		if !s.reserveKey(event.Occurrences) {
+			return
+		}
+		idx = len(s.undocumentedStatusCodes)
+		s.undocumentedStatusIndex[key] = idx
+		s.undocumentedStatusCodes = append(s.undocumentedStatusCodes, UndocumentedStatusCode{
+			ClusterName:   event.ClusterName,
+			ServiceName:   event.ServiceName,
+			RequestMethod: operation.Method,
+			SpecPath:      operation.PathTemplate,
+			Spec:          spec.cfg.Name,
+			StatusCode:    event.ResponseCode,
+		})
+	}
+	s.undocumentedStatusCodes[idx].Occurrences += event.Occurrences
+}
+
+// documentsStatusCode reports whether the documented response codes, e.g.
+// `200`, `2XX` or `default`, cover the status code.
+func documentsStatusCode(responseCodes []string, statusCode int) bool {
+	code := strconv.Itoa(statusCode)
+	for _, responseCode := range responseCodes {
+		if responseCode == "default" || responseCode == code {
+			return true
+		}
+		if len(responseCode) == 3 && len(code) == 3 && strings.EqualFold(responseCode[1:], "XX") && responseCode[0] == code[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotFoundStatus reports whether the status code is one routers answer with
+// when no handler serves the request.
+func isNotFoundStatus(statusCode int) bool {
+	return statusCode == http.StatusNotFound || statusCode == http.StatusMethodNotAllowed
+}
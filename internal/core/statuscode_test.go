@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+func TestDocumentsStatusCode(t *testing.T) {
+	tests := []struct {
+		name          string
+		responseCodes []string
+		statusCode    int
+		want          bool
+	}{
+		{name: "exact code", responseCodes: []string{"200", "404"}, statusCode: 404, want: true},
+		{name: "undocumented code", responseCodes: []string{"200", "404"}, statusCode: 500, want: false},
+		{name: "range", responseCodes: []string{"2XX"}, statusCode: 204, want: true},
+		{name: "lowercase range", responseCodes: []string{"4xx"}, statusCode: 429, want: true},
+		{name: "other range", responseCodes: []string{"2XX"}, statusCode: 301, want: false},
+		{name: "default", responseCodes: []string{"200", "default"}, statusCode: 503, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, documentsStatusCode(tt.responseCodes, tt.statusCode))
+		})
+	}
+}
+
+func TestScan_UndocumentedStatusCodes(t *testing.T) {
+	inv := &inventory.Inventory{
+		PathItems: []*inventory.PathItem{
+			{
+				PathTemplate: "/users/{id}",
+				Operations: []*inventory.Operation{
+					{Method: "GET", PathTemplate: "/users/{id}", ResponseCodes: []string{"200", "4XX"}},
+					{Method: "DELETE", PathTemplate: "/users/{id}", ResponseCodes: []string{"204", "default"}},
+					{Method: "PUT", PathTemplate: "/users/{id}"},
+				},
+			},
+		},
+	}
+
+	report := scanEvents(newTestManager(), inv,
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/1", ResponseCode: 200},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/2", ResponseCode: 404},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/3", ResponseCode: 500, Occurrences: 2},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/4", ResponseCode: 500},
+		// Unknown status codes are not compared.
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users/5"},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "DELETE", RequestPath: "/users/1", ResponseCode: 503},
+		// Operations without documented responses are not compared.
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "PUT", RequestPath: "/users/1", ResponseCode: 500},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts", ResponseCode: 500},
+	)
+
+	assert.Equal(t, []UndocumentedStatusCode{
+		{ServiceName: "svc", RequestMethod: "GET", SpecPath: "/users/{id}", Spec: "test", StatusCode: 500, Occurrences: 3},
+	}, report.UndocumentedStatusCodes)
+}
+
+func TestScan_ExcludeNotFoundShadowApis(t *testing.T) {
+	events := []apievent.ApiEvent{
+		{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/wp-admin", ResponseCode: 404},
+		{ServiceName: "svc", RequestMethod: "POST", RequestPath: "/users/1", ResponseCode: 405},
+		{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts", ResponseCode: 404},
+		{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/carts", ResponseCode: 200},
+		// Unknown status codes may have reached a handler.
+		{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/metrics"},
+	}
+
+	report := scanEvents(newTestManager(), newTestInventory(), events...)
+	assert.Len(t, report.ShadowAPIs, 4)
+	assert.Zero(t, report.Stats.ExcludedShadowApis)
+
+	m := newTestManager()
+	m.Cfg.Detection.ExcludeNotFoundShadowApis = true
+	report = scanEvents(m, newTestInventory(), events...)
+	var paths []string
+	for _, api := range report.ShadowAPIs {
+		paths = append(paths, api.RequestPath)
+	}
+	assert.Equal(t, []string{"/carts", "/metrics"}, paths)
+	assert.Equal(t, 2, report.Stats.ExcludedShadowApis)
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/5gsec/api-speculator/internal/config"
+	"github.com/5gsec/api-speculator/internal/util"
+)
+
+// applySuppressions moves the findings of the report that a suppression
+// applies to into its suppressed section. Expired suppressions no longer apply
+// and are reported as warnings.
+func (m *Manager) applySuppressions(report *apiReport, now time.Time) {
+	var suppressions []config.Suppression
+	for _, suppression := range m.Cfg.Suppressions {
+		if suppression.Expired(now) {
+			message := fmt.Sprintf("suppression of the %s API `%s %s` owned by `%s` expired on %s",
+				suppression.FindingType, suppression.Method, suppression.Path, suppression.Owner, suppression.Expires.Format(time.DateOnly))
+			m.Logger.Warn(message)
+			report.Warnings = append(report.Warnings, ScanWarning{Type: ScanWarningSuppressionExpired, Message: message})
+			continue
+		}
+		suppressions = append(suppressions, suppression)
+	}
+	if len(suppressions) == 0 {
+		return
+	}
+
+	report.ShadowAPIs = suppressFindings(report, config.FindingTypeShadow, report.ShadowAPIs, suppressions)
+	report.ZombieAPIs = suppressFindings(report, config.FindingTypeZombie, report.ZombieAPIs, suppressions)
+	report.OrphanAPIs = suppressFindings(report, config.FindingTypeOrphan, report.OrphanAPIs, suppressions)
+}
+
+// suppressFindings returns the findings no suppression applies to, adding the
+// other ones to the suppressed section of the report.
+func suppressFindings(report *apiReport, findingType string, apis []API, suppressions []config.Suppression) []API {
+	var kept []API
+	for _, api := range apis {
+		suppression, suppressed := findSuppression(findingType, api, suppressions)
+		if !suppressed {
+			kept = append(kept, api)
+			continue
+		}
+		report.Suppressed = append(report.Suppressed, SuppressedFinding{
+			API:         api,
+			FindingType: findingType,
+			Owner:       suppression.Owner,
+			Reason:      suppression.Reason,
+			Expires:     suppression.Expires,
+		})
+	}
+	return kept
+}
+
+// findSuppression returns the first suppression that applies to the finding.
+func findSuppression(findingType string, api API, suppressions []config.Suppression) (config.Suppression, bool) {
+	for _, suppression := range suppressions {
+		if suppression.FindingType != findingType {
+			continue
+		}
+		if suppression.Method != "" && !strings.EqualFold(suppression.Method, api.RequestMethod) {
+			continue
+		}
+		if suppression.Service != "" && !matchesAny([]string{suppression.Service}, api.ServiceName) {
+			continue
+		}
+		if matchesPathTemplate(suppression.Path, api.RequestPath) ||
+			(api.SpecPath != "" && matchesPathTemplate(suppression.Path, api.SpecPath)) {
+			return suppression, true
+		}
+	}
+	return config.Suppression{}, false
+}
+
+// matchesPathTemplate reports whether the path matches the template, a
+// template parameter matches any single non-empty path segment.
+func matchesPathTemplate(template, requestPath string) bool {
+	templateSegments := strings.Split(template, "/")
+	pathSegments := strings.Split(requestPath, "/")
+	if len(templateSegments) != len(pathSegments) {
+		return false
+	}
+	for idx, segment := range templateSegments {
+		if util.IsPathParam(segment) && pathSegments[idx] != "" {
+			continue
+		}
+		if segment != pathSegments[idx] {
+			return false
+		}
+	}
+	return true
+}
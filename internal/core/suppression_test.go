@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/5gsec/api-speculator/internal/config"
+)
+
+func TestApplySuppressions(t *testing.T) {
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+	m := newTestManager()
+	m.Cfg.Suppressions = []config.Suppression{
+		{FindingType: config.FindingTypeShadow, Method: "get", Path: "/carts/{id}", Service: "shop*", Owner: "team-shop", Reason: "accepted risk"},
+		{FindingType: config.FindingTypeZombie, Path: "/orders/{orderId}", Owner: "team-orders", Reason: "migrating", Expires: now.AddDate(0, 0, 7)},
+		{FindingType: config.FindingTypeOrphan, Path: "/users/{id}", Owner: "team-users", Reason: "expired", Expires: now.AddDate(0, 0, -1)},
+	}
+
+	report := apiReport{
+		ShadowAPIs: []API{
+			{ServiceName: "shop.default", RequestMethod: "GET", RequestPath: "/carts/42"},
+			{ServiceName: "shop.default", RequestMethod: "DELETE", RequestPath: "/carts/42"},
+			{ServiceName: "users", RequestMethod: "GET", RequestPath: "/carts/42"},
+		},
+		ZombieAPIs: []API{
+			{ServiceName: "orders", RequestMethod: "GET", RequestPath: "/api/orders/1", SpecPath: "/orders/{id}"},
+		},
+		OrphanAPIs: []API{
+			{RequestMethod: "GET", RequestPath: "/users/{id}"},
+		},
+	}
+	m.applySuppressions(&report, now)
+
+	assert.Equal(t, []API{
+		{ServiceName: "shop.default", RequestMethod: "DELETE", RequestPath: "/carts/42"},
+		{ServiceName: "users", RequestMethod: "GET", RequestPath: "/carts/42"},
+	}, report.ShadowAPIs)
+	assert.Empty(t, report.ZombieAPIs)
+	assert.Equal(t, []API{{RequestMethod: "GET", RequestPath: "/users/{id}"}}, report.OrphanAPIs)
+	assert.Equal(t, []SuppressedFinding{
+		{
+			API:         API{ServiceName: "shop.default", RequestMethod: "GET", RequestPath: "/carts/42"},
+			FindingType: config.FindingTypeShadow, Owner: "team-shop", Reason: "accepted risk",
+		},
+		{
+			API:         API{ServiceName: "orders", RequestMethod: "GET", RequestPath: "/api/orders/1", SpecPath: "/orders/{id}"},
+			FindingType: config.FindingTypeZombie, Owner: "team-orders", Reason: "migrating", Expires: now.AddDate(0, 0, 7),
+		},
+	}, report.Suppressed)
+	assert.Len(t, report.Warnings, 1)
+	assert.Equal(t, ScanWarningSuppressionExpired, report.Warnings[0].Type)
+}
+
+func TestMatchesPathTemplate(t *testing.T) {
+	assert.True(t, matchesPathTemplate("/users/{id}", "/users/42"))
+	assert.True(t, matchesPathTemplate("/users/{userId}", "/users/{id}"))
+	assert.True(t, matchesPathTemplate("/users", "/users"))
+	assert.False(t, matchesPathTemplate("/users/{id}", "/users/"))
+	assert.False(t, matchesPathTemplate("/users/{id}", "/users/42/orders"))
+	assert.False(t, matchesPathTemplate("/users/{id}", "/carts/42"))
+}
@@ -4,61 +4,159 @@
 package core
 
 import (
-	"io"
-	"net/http"
+	"fmt"
 	"os"
+	"slices"
 	"strings"
 
-	"github.com/pb33f/libopenapi"
-	v3 "github.com/pb33f/libopenapi/datamodel/high/v3"
-
 	"github.com/5gsec/api-speculator/internal/apispec"
+	"github.com/5gsec/api-speculator/internal/config"
+	"github.com/5gsec/api-speculator/internal/inventory"
 	"github.com/5gsec/api-speculator/internal/pathtrie"
 )
 
-func (m *Manager) buildModel(oasCfg string) (*libopenapi.DocumentModel[v3.Document], error) {
-	var err error
-	var specBytes []byte
+// SpecLoadError reports a spec, or one of its files, that could not be loaded.
+type SpecLoadError struct {
+	// Spec is the name of the configured spec.
+	Spec string
+	// Source is the file path or URL that failed to load.
+	Source string
+	Err    error
+}
 
-	if strings.HasPrefix(oasCfg, "http://") || strings.HasPrefix(oasCfg, "https://") {
-		specBytes, err = downloadSpec(oasCfg)
+func (e *SpecLoadError) Error() string {
+	return fmt.Sprintf("failed to load `%s` spec from `%s`: %v", e.Spec, e.Source, e.Err)
+}
+
+func (e *SpecLoadError) Unwrap() error {
+	return e.Err
+}
+
+// buildModel loads the spec(s) found at the configured location, either a URL,
+// a file, a directory or a glob pattern, into a single inventory. Files that
+// fail to load are skipped and returned as SpecLoadError, the inventory is nil
+// if nothing could be loaded.
+func (m *Manager) buildModel(specCfg config.Spec) (*inventory.Inventory, []error) {
+	oasCfg := specCfg.Path
+	if apispec.IsURL(oasCfg) {
+		downloader, err := apispec.NewDownloader(oasCfg, specCfg.Download)
+		if err != nil {
+			return nil, []error{&SpecLoadError{Source: oasCfg, Err: err}}
+		}
+		specBytes, err := downloader.Download(oasCfg)
 		if err != nil {
-			return nil, err
+			return nil, []error{&SpecLoadError{Source: oasCfg, Err: err}}
 		}
-	} else {
-		specBytes, err = os.ReadFile(oasCfg)
+		inv, err := apispec.BuildInventory(specBytes, oasCfg, downloader.Get)
 		if err != nil {
-			return nil, err
+			return nil, []error{&SpecLoadError{Source: oasCfg, Err: err}}
 		}
+		return inv, nil
 	}
 
-	model, err := apispec.BuildOASV3Model(specBytes)
+	specFiles, expanded, err := apispec.FindSpecFiles(oasCfg)
 	if err != nil {
-		m.Logger.Error(err)
-		return nil, nil
+		return nil, []error{&SpecLoadError{Source: oasCfg, Err: err}}
 	}
-	return model, nil
 
-}
+	var errs []error
+	inv := &inventory.Inventory{Source: oasCfg}
+	loadedFiles := 0
+	for _, specFile := range specFiles {
+		specBytes, err := os.ReadFile(specFile)
+		if err != nil {
+			errs = append(errs, &SpecLoadError{Source: specFile, Err: err})
+			continue
+		}
+		if expanded {
+			isRootDocument, err := apispec.IsRootDocument(specBytes)
+			if err != nil {
+				errs = append(errs, &SpecLoadError{Source: specFile, Err: err})
+				continue
+			}
+			if !isRootDocument {
+				// Most likely a fragment referenced by another spec.
+				m.Logger.Debugf("skipping `%s`, not an OpenAPI or Swagger document", specFile)
+				continue
+			}
+		}
 
-func downloadSpec(oasCfg string) ([]byte, error) {
-	response, err := http.Get(oasCfg)
-	if err != nil {
-		return nil, err
+		fileInv, err := apispec.BuildInventory(specBytes, specFile, nil)
+		if err != nil {
+			errs = append(errs, &SpecLoadError{Source: specFile, Err: err})
+			continue
+		}
+		inv.Merge(fileInv)
+		loadedFiles++
 	}
-	defer response.Body.Close()
 
-	data, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
+	if loadedFiles == 0 {
+		if len(errs) == 0 {
+			errs = append(errs, &SpecLoadError{Source: oasCfg, Err: fmt.Errorf("no OpenAPI or Swagger document found")})
+		}
+		return nil, errs
 	}
-	return data, nil
+	return inv, errs
 }
 
-func (m *Manager) buildTrie(model *libopenapi.DocumentModel[v3.Document]) pathtrie.PathTrie {
+// buildTrie inserts every path of the inventory under each of its base paths,
+// as rewritten by the path prefixes configuration.
+func (m *Manager) buildTrie(inv *inventory.Inventory, pathPrefixes config.PathPrefixes) pathtrie.PathTrie {
 	trie := pathtrie.New()
-	for paths := model.Model.Paths.PathItems.First(); paths != nil; paths = paths.Next() {
-		_ = trie.Insert(paths.Key(), paths.Value())
+	for _, pathItem := range inv.PathItems {
+		for _, servedPath := range servedPaths(pathItem, pathPrefixes) {
+			_ = trie.Insert(servedPath, pathItem)
+		}
 	}
 	return trie
 }
+
+// servedPaths returns the path template of the path item prefixed with each of
+// its base paths, as rewritten by the path prefixes configuration.
+func servedPaths(pathItem *inventory.PathItem, pathPrefixes config.PathPrefixes) []string {
+	var paths []string
+	for _, basePath := range rewriteBasePaths(pathItem.BasePaths, pathPrefixes) {
+		paths = append(paths, joinPath(basePath, pathItem.PathTemplate))
+	}
+	return paths
+}
+
+// rewriteBasePaths strips the configured prefixes from the base paths and adds
+// the configured ones. An empty base path stands for the root.
+func rewriteBasePaths(basePaths []string, pathPrefixes config.PathPrefixes) []string {
+	if len(basePaths) == 0 {
+		basePaths = []string{""}
+	}
+
+	var rewritten []string
+	for _, basePath := range basePaths {
+		for _, strip := range pathPrefixes.Strip {
+			strip = strings.TrimSuffix(strip, "/")
+			if basePath == strip || strings.HasPrefix(basePath, strip+"/") {
+				basePath = strings.TrimPrefix(basePath, strip)
+				break
+			}
+		}
+		if !slices.Contains(rewritten, basePath) {
+			rewritten = append(rewritten, basePath)
+		}
+	}
+	for _, add := range pathPrefixes.Add {
+		add = strings.TrimSuffix(add, "/")
+		if !slices.Contains(rewritten, add) {
+			rewritten = append(rewritten, add)
+		}
+	}
+	return rewritten
+}
+
+// joinPath prefixes the path template with the base path.
+func joinPath(basePath, pathTemplate string) string {
+	if basePath == "" {
+		return pathTemplate
+	}
+	if pathTemplate == "/" {
+		return basePath
+	}
+	return basePath + pathTemplate
+}
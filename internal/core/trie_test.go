@@ -0,0 +1,230 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/5gsec/api-speculator/internal/apievent"
+	"github.com/5gsec/api-speculator/internal/config"
+	"github.com/5gsec/api-speculator/internal/inventory"
+)
+
+func TestBuildModel_Directory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"users.yaml": `openapi: 3.0.3
+info:
+  title: users
+  version: 1.0.0
+paths:
+  /users:
+    get:
+      responses:
+        "200":
+          description: ok
+`,
+		"orders.yaml": `swagger: "2.0"
+info:
+  title: orders
+  version: 1.0.0
+basePath: /v1
+paths:
+  /orders:
+    get:
+      responses:
+        "200":
+          description: ok
+`,
+		"broken.yaml": `openapi: 3.0.3
+paths: [not, valid
+`,
+		"fragment.yaml": `user:
+  type: object
+`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	m := newTestManager()
+	inv, errs := m.buildModel(config.Spec{Path: dir})
+	require.NotNil(t, inv)
+	require.Len(t, errs, 1)
+
+	var specErr *SpecLoadError
+	require.ErrorAs(t, errs[0], &specErr)
+	assert.Equal(t, filepath.Join(dir, "broken.yaml"), specErr.Source)
+
+	var pathTemplates []string
+	for _, pathItem := range inv.PathItems {
+		pathTemplates = append(pathTemplates, pathItem.PathTemplate)
+	}
+	assert.ElementsMatch(t, []string{"/users", "/orders"}, pathTemplates)
+
+	inv, errs = m.buildModel(config.Spec{Path: filepath.Join(dir, "broken.yaml")})
+	assert.Nil(t, inv)
+	assert.Len(t, errs, 1)
+}
+
+// writeSharedTemplateSpecs writes two specs documenting `/items` under other
+// servers to a directory.
+func writeSharedTemplateSpecs(t *testing.T) string {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml": `openapi: 3.0.3
+info:
+  title: users
+  version: 1.0.0
+servers:
+  - url: /users-api
+paths:
+  /items:
+    get:
+      responses:
+        "200":
+          description: ok
+`,
+		"b.yaml": `openapi: 3.0.3
+info:
+  title: orders
+  version: 1.0.0
+servers:
+  - url: /orders-api
+paths:
+  /items:
+    get:
+      responses:
+        "200":
+          description: ok
+    post:
+      responses:
+        "201":
+          description: created
+`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func TestBuildModel_SharedTemplateOnOtherServers(t *testing.T) {
+	dir := writeSharedTemplateSpecs(t)
+
+	m := newTestManager()
+	inv, errs := m.buildModel(config.Spec{Path: dir})
+	require.Empty(t, errs)
+	require.NotNil(t, inv)
+	assert.Len(t, inv.Operations(), 3)
+
+	trie := m.buildTrie(inv, config.PathPrefixes{})
+	tests := []struct {
+		path       string
+		method     string
+		wantSource string
+	}{
+		{path: "/users-api/items", method: "GET", wantSource: "a.yaml"},
+		{path: "/users-api/items", method: "POST"},
+		{path: "/orders-api/items", method: "GET", wantSource: "b.yaml"},
+		{path: "/orders-api/items", method: "POST", wantSource: "b.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			_, value, found := trie.GetPathAndValue(tt.path)
+			require.True(t, found)
+			operation := value.(*inventory.PathItem).GetOperation(tt.method)
+			if tt.wantSource == "" {
+				assert.Nil(t, operation)
+				return
+			}
+			require.NotNil(t, operation)
+			assert.Equal(t, filepath.Join(dir, tt.wantSource), operation.Source)
+		})
+	}
+}
+
+func TestScan_SharedTemplateOnOtherServers(t *testing.T) {
+	m := newTestManager()
+	inv, errs := m.buildModel(config.Spec{Path: writeSharedTemplateSpecs(t)})
+	require.Empty(t, errs)
+
+	report := scanEvents(m, inv,
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "GET", RequestPath: "/users-api/items", ResponseCode: 200},
+		apievent.ApiEvent{ServiceName: "svc", RequestMethod: "POST", RequestPath: "/orders-api/items", ResponseCode: 500},
+	)
+
+	assert.Empty(t, report.ShadowAPIs)
+	assert.Equal(t, []API{{RequestMethod: "GET", RequestPath: "/orders-api/items", SpecPath: "/items", Spec: "test"}}, report.OrphanAPIs)
+	assert.Equal(t, []UndocumentedStatusCode{
+		{ServiceName: "svc", RequestMethod: "POST", SpecPath: "/items", Spec: "test", StatusCode: 500, Occurrences: 1},
+	}, report.UndocumentedStatusCodes)
+}
+
+func TestBuildTrie_BasePaths(t *testing.T) {
+	inv := &inventory.Inventory{
+		PathItems: []*inventory.PathItem{
+			{
+				PathTemplate: "/users/{id}",
+				BasePaths:    []string{"/api/v2"},
+				Operations:   []*inventory.Operation{{Method: "GET", PathTemplate: "/users/{id}"}},
+			},
+			{
+				PathTemplate: "/",
+				BasePaths:    []string{"/api/v2"},
+				Operations:   []*inventory.Operation{{Method: "GET", PathTemplate: "/"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		pathPrefixes config.PathPrefixes
+		path         string
+		wantFound    bool
+	}{
+		{name: "served under the base path", path: "/api/v2/users/42", wantFound: true},
+		{name: "root path under the base path", path: "/api/v2", wantFound: true},
+		{name: "not served at the root", path: "/users/42", wantFound: false},
+		{
+			name:         "stripped prefix",
+			pathPrefixes: config.PathPrefixes{Strip: []string{"/api/"}},
+			path:         "/v2/users/42",
+			wantFound:    true,
+		},
+		{
+			name:         "stripped prefix is no longer served",
+			pathPrefixes: config.PathPrefixes{Strip: []string{"/api"}},
+			path:         "/api/v2/users/42",
+			wantFound:    false,
+		},
+		{
+			name:         "added prefix",
+			pathPrefixes: config.PathPrefixes{Add: []string{"/gateway"}},
+			path:         "/gateway/users/42",
+			wantFound:    true,
+		},
+	}
+
+	m := newTestManager()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trie := m.buildTrie(inv, tt.pathPrefixes)
+			_, _, found := trie.GetPathAndValue(tt.path)
+			assert.Equal(t, tt.wantFound, found)
+		})
+	}
+}
+
+func TestRewriteBasePaths(t *testing.T) {
+	assert.Equal(t, []string{""}, rewriteBasePaths(nil, config.PathPrefixes{}))
+	assert.Equal(t, []string{"/v2", "/v1", "/api"}, rewriteBasePaths([]string{"/api/v2", "/v1"},
+		config.PathPrefixes{Strip: []string{"/api", "/v1/x"}, Add: []string{"/v2", "/api/"}}))
+	assert.Equal(t, []string{"/apiv2"}, rewriteBasePaths([]string{"/apiv2"}, config.PathPrefixes{Strip: []string{"/api"}}))
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package inventory
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Inventory is a version-neutral representation of the API operations
+// documented by a specification. Spec loaders populate it and detectors
+// consume it.
+type Inventory struct {
+	// Source is the file path or URL of the specification.
+	Source string
+
+	// Version of the specification, e.g. "2.0" or "3.0.3".
+	Version string
+
+	// PathItems holds the documented paths in specification order.
+	PathItems []*PathItem
+}
+
+type PathItem struct {
+	// PathTemplate as it is documented, e.g. excluding the Swagger basePath.
+	PathTemplate string
+
+	// BasePaths lists the path prefixes the path is served under, derived from
+	// the OpenAPI servers or the Swagger basePath. Empty if the path is served at
+	// the root.
+	BasePaths []string
+
+	// Operations holds the operations documented on the path in specification
+	// order.
+	Operations []*Operation
+}
+
+type Operation struct {
+	// Method is the upper-case request method.
+	Method string
+
+	// PathTemplate of the path the operation is documented on.
+	PathTemplate string
+
+	// Params holds the path, query, header and cookie parameters of the
+	// operation, including the ones inherited from its path.
+	Params []Param
+
+	Deprecated bool
+
+	// Sunset is the date the operation is planned to be removed, taken from the
+	// `x-sunset` extension. Nil if not documented.
+	Sunset *time.Time
+
+	// Security lists the names of the security schemes the operation requires.
+	Security []string
+
+	// ResponseCodes lists the documented response status codes, e.g. "200",
+	// "4XX" or "default".
+	ResponseCodes []string
+
+	// Schemes lists the transfer protocols of the operation, if documented.
+	Schemes []string
+
+	// Source is the file path or URL of the specification documenting the
+	// operation.
+	Source string
+}
+
+// Param describes a documented operation parameter.
+type Param struct {
+	Name     string
+	In       string
+	Required bool
+	Schema   Schema
+}
+
+// Schema holds the parts of a parameter schema that can be validated against
+// observed values.
+type Schema struct {
+	Type   string
+	Format string
+
+	// Pattern is a regular expression the value must match, not anchored.
+	Pattern string
+
+	// Enum lists the allowed values, formatted as strings.
+	Enum []string
+
+	// MinLength and MaxLength bound the number of characters of string values,
+	// nil if unbounded.
+	MinLength *int64
+	MaxLength *int64
+}
+
+// GetOperation returns the operation documented for the given request method,
+// nil if there is no such operation.
+func (p *PathItem) GetOperation(requestMethod string) *Operation {
+	if p == nil {
+		return nil
+	}
+	for _, operation := range p.Operations {
+		if strings.EqualFold(operation.Method, requestMethod) {
+			return operation
+		}
+	}
+	return nil
+}
+
+// Operations returns every operation of the inventory in specification order.
+func (inv *Inventory) Operations() []*Operation {
+	var operations []*Operation
+	for _, pathItem := range inv.PathItems {
+		operations = append(operations, pathItem.Operations...)
+	}
+	return operations
+}
+
+// Merge adds the path items of other to the inventory. Operations documented on
+// a path template the inventory already holds under the same base paths are
+// added to the existing path item, unless the inventory already documents the
+// same method. Path items served under other base paths are kept apart.
+func (inv *Inventory) Merge(other *Inventory) {
+	if inv.Version == "" {
+		inv.Version = other.Version
+	}
+
+	for _, otherPathItem := range other.PathItems {
+		pathItem := inv.getPathItem(otherPathItem.PathTemplate, otherPathItem.BasePaths)
+		if pathItem == nil {
+			inv.PathItems = append(inv.PathItems, otherPathItem)
+			continue
+		}
+		for _, operation := range otherPathItem.Operations {
+			if pathItem.GetOperation(operation.Method) == nil {
+				pathItem.Operations = append(pathItem.Operations, operation)
+			}
+		}
+	}
+}
+
+func (inv *Inventory) getPathItem(pathTemplate string, basePaths []string) *PathItem {
+	for _, pathItem := range inv.PathItems {
+		if pathItem.PathTemplate == pathTemplate && slices.Equal(pathItem.BasePaths, basePaths) {
+			return pathItem
+		}
+	}
+	return nil
+}
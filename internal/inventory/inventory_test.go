@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathItem_GetOperation(t *testing.T) {
+	pathItem := &PathItem{
+		PathTemplate: "/users",
+		Operations:   []*Operation{{Method: "GET", PathTemplate: "/users"}},
+	}
+
+	assert.Equal(t, pathItem.Operations[0], pathItem.GetOperation("get"))
+	assert.Nil(t, pathItem.GetOperation("POST"))
+	assert.Nil(t, (*PathItem)(nil).GetOperation("GET"))
+}
+
+func TestInventory_Operations(t *testing.T) {
+	inv := &Inventory{
+		PathItems: []*PathItem{
+			{PathTemplate: "/users", Operations: []*Operation{{Method: "GET"}, {Method: "POST"}}},
+			{PathTemplate: "/orders", Operations: []*Operation{{Method: "GET"}}},
+		},
+	}
+
+	operations := inv.Operations()
+	assert.Len(t, operations, 3)
+	assert.Equal(t, "POST", operations[1].Method)
+}
+
+func TestInventory_Merge(t *testing.T) {
+	inv := &Inventory{
+		PathItems: []*PathItem{
+			{PathTemplate: "/users", Operations: []*Operation{{Method: "GET", Source: "a.yaml"}}},
+		},
+	}
+	inv.Merge(&Inventory{
+		Version: "3.0.3",
+		PathItems: []*PathItem{
+			{PathTemplate: "/users", Operations: []*Operation{{Method: "GET", Source: "b.yaml"}, {Method: "POST", Source: "b.yaml"}}},
+			{PathTemplate: "/orders", Operations: []*Operation{{Method: "GET", Source: "b.yaml"}}},
+			{PathTemplate: "/users", BasePaths: []string{"/v2"}, Operations: []*Operation{{Method: "GET", Source: "b.yaml"}}},
+		},
+	})
+
+	assert.Equal(t, "3.0.3", inv.Version)
+	assert.Equal(t, []*PathItem{
+		{PathTemplate: "/users", Operations: []*Operation{{Method: "GET", Source: "a.yaml"}, {Method: "POST", Source: "b.yaml"}}},
+		{PathTemplate: "/orders", Operations: []*Operation{{Method: "GET", Source: "b.yaml"}}},
+		{PathTemplate: "/users", BasePaths: []string{"/v2"}, Operations: []*Operation{{Method: "GET", Source: "b.yaml"}}},
+	}, inv.PathItems)
+}
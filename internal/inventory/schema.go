@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package inventory
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// compiledPatterns caches the compiled schema patterns, by pattern. Patterns
+// that don't compile are cached as nil and not validated.
+var compiledPatterns sync.Map
+
+// Validate returns an error describing why the observed value violates the
+// schema, nil if it doesn't. Unknown types and formats, and patterns that are
+// not valid regular expressions, are not validated.
+func (s Schema) Validate(value string) error {
+	switch s.Type {
+	case "integer":
+		bitSize := 64
+		if s.Format == "int32" {
+			bitSize = 32
+		}
+		if _, err := strconv.ParseInt(value, 10, bitSize); err != nil {
+			return fmt.Errorf("expects an integer")
+		}
+	case "number":
+		if number, err := strconv.ParseFloat(value, 64); err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return fmt.Errorf("expects a number")
+		}
+	case "boolean":
+		if value != "true" && value != "false" {
+			return fmt.Errorf("expects a boolean")
+		}
+	case "string":
+		if err := s.validateFormat(value); err != nil {
+			return err
+		}
+	}
+
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, value) {
+		return fmt.Errorf("expects one of the enum values")
+	}
+	length := int64(utf8.RuneCountInString(value))
+	if s.MinLength != nil && length < *s.MinLength {
+		return fmt.Errorf("expects at least %d characters", *s.MinLength)
+	}
+	if s.MaxLength != nil && length > *s.MaxLength {
+		return fmt.Errorf("expects at most %d characters", *s.MaxLength)
+	}
+	if pattern := compilePattern(s.Pattern); pattern != nil && !pattern.MatchString(value) {
+		return fmt.Errorf("expects a value matching `%s`", s.Pattern)
+	}
+	return nil
+}
+
+func (s Schema) validateFormat(value string) error {
+	switch s.Format {
+	case "uuid":
+		if !uuidPattern.MatchString(value) {
+			return fmt.Errorf("expects a uuid")
+		}
+	case "date":
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return fmt.Errorf("expects a date")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("expects a date-time")
+		}
+	}
+	return nil
+}
+
+func compilePattern(pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	if compiled, exists := compiledPatterns.Load(pattern); exists {
+		return compiled.(*regexp.Regexp)
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		compiled = nil
+	}
+	compiledPatterns.Store(pattern, compiled)
+	return compiled
+}
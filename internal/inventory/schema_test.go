@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2024 Authors of API-Speculator
+
+package inventory
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	minLength, maxLength := int64(2), int64(4)
+	tests := []struct {
+		name    string
+		schema  Schema
+		valid   []string
+		invalid []string
+	}{
+		{"integer", Schema{Type: "integer"}, []string{"42", "-1"}, []string{"abc", "4.2", ""}},
+		{"int32", Schema{Type: "integer", Format: "int32"}, []string{"2147483647"}, []string{"2147483648"}},
+		{"number", Schema{Type: "number"}, []string{"4.2", "1e3"}, []string{"abc", "NaN"}},
+		{"boolean", Schema{Type: "boolean"}, []string{"true", "false"}, []string{"yes"}},
+		{"uuid", Schema{Type: "string", Format: "uuid"}, []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, []string{"export"}},
+		{"date", Schema{Type: "string", Format: "date"}, []string{"2024-05-01"}, []string{"2024-13-01", "today"}},
+		{"date-time", Schema{Type: "string", Format: "date-time"}, []string{"2024-05-01T10:00:00Z"}, []string{"2024-05-01"}},
+		{"pattern", Schema{Type: "string", Pattern: "^[a-z]+$"}, []string{"abc"}, []string{"ABC"}},
+		{"invalid pattern", Schema{Type: "string", Pattern: "(?<=a)b"}, []string{"anything"}, nil},
+		{"enum", Schema{Type: "string", Enum: []string{"asc", "desc"}}, []string{"asc"}, []string{"up"}},
+		{"length", Schema{Type: "string", MinLength: &minLength, MaxLength: &maxLength}, []string{"ab", "abcd", "éé"}, []string{"a", "abcde"}},
+		{"untyped", Schema{}, []string{"anything"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, value := range tt.valid {
+				assert.NoError(t, tt.schema.Validate(value), value)
+			}
+			for _, value := range tt.invalid {
+				assert.Error(t, tt.schema.Validate(value), value)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@
 package pathtrie
 
 import (
+	"slices"
 	"strings"
 
 	"github.com/5gsec/api-speculator/internal/util"
@@ -104,9 +105,13 @@ func (pt *PathTrie) Insert(path string, val any) bool {
 	})
 }
 
+// AcceptFunc reports whether a node matching a path, given its full path and
+// value, is an acceptable match.
+type AcceptFunc func(fullPath string, value any) bool
+
 // GetValue returns the given node path value, nil if node is not found.
 func (pt *PathTrie) GetValue(path string) any {
-	node := pt.getNode(path)
+	node := pt.getNode(path, nil)
 	if node == nil {
 		return nil
 	}
@@ -116,7 +121,14 @@ func (pt *PathTrie) GetValue(path string) any {
 
 // GetPathAndValue returns the given node full path and value, nil if node is not found.
 func (pt *PathTrie) GetPathAndValue(path string) (string, any, bool) {
-	node := pt.getNode(path)
+	return pt.GetPathAndValueFunc(path, nil)
+}
+
+// GetPathAndValueFunc is like GetPathAndValue, but only considers the matching
+// nodes accept accepts, e.g. to validate path parameter values. Every matching
+// node is accepted if accept is nil.
+func (pt *PathTrie) GetPathAndValueFunc(path string, accept AcceptFunc) (string, any, bool) {
+	node := pt.getNode(path, accept)
 	if node == nil {
 		return "", nil, false
 	}
@@ -124,10 +136,15 @@ func (pt *PathTrie) GetPathAndValue(path string) (string, any, bool) {
 	return node.FullPath, node.Value, true
 }
 
-func (pt *PathTrie) getNode(path string) *TrieNode {
+func (pt *PathTrie) getNode(path string, accept AcceptFunc) *TrieNode {
 	segments := strings.Split(path, pt.PathSeparator)
 
 	nodes := pt.Trie.getMatchNodes(segments, 0)
+	if accept != nil {
+		nodes = slices.DeleteFunc(nodes, func(node *TrieNode) bool {
+			return !accept(node.FullPath, node.Value)
+		})
+	}
 
 	if len(nodes) == 0 {
 		return nil
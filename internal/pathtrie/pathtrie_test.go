@@ -133,7 +133,7 @@ func TestPathTrie_getNode(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := pt.getNode(tt.args.path); !reflect.DeepEqual(got, tt.want) {
+			if got := pt.getNode(tt.args.path, nil); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("getNode() = %v, want %v", got, tt.want)
 			}
 		})
@@ -232,6 +232,31 @@ func TestPathTrie_GetPathAndValue(t *testing.T) {
 	}
 }
 
+func TestPathTrie_GetPathAndValueFunc(t *testing.T) {
+	pt := New()
+	if err := populateDummyPathsAndValue(pt,
+		pathAndValue{path: "/api/{param1}/items", value: 1},
+		pathAndValue{path: "/api/{param1}/{param2}", value: 2},
+	); err != nil {
+		t.Error(err)
+	}
+
+	// The most accurate node is rejected, the next one is returned.
+	gotPath, gotValue, gotFound := pt.GetPathAndValueFunc("/api/1/items", func(fullPath string, value any) bool {
+		return value != 1
+	})
+	if gotPath != "/api/{param1}/{param2}" || gotValue != 2 || !gotFound {
+		t.Errorf("GetPathAndValueFunc() = %v, %v, %v", gotPath, gotValue, gotFound)
+	}
+
+	_, _, gotFound = pt.GetPathAndValueFunc("/api/1/items", func(string, any) bool {
+		return false
+	})
+	if gotFound {
+		t.Errorf("GetPathAndValueFunc() found a rejected node")
+	}
+}
+
 func TestPathTrieMap_getMatchNodes(t *testing.T) {
 	type args struct {
 		segments []string
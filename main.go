@@ -4,9 +4,13 @@
 package main
 
 import (
+	"os"
+
 	"github.com/5gsec/api-speculator/cmd"
 )
 
 func main() {
-	_ = cmd.RootCmd.Execute()
+	if err := cmd.RootCmd.Execute(); err != nil {
+		os.Exit(1)
+	}
 }